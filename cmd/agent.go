@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"agent/gorani/internal/agent"
+	"agent/gorani/internal/prompt"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	agentModel    string
+	agentMaxSteps int
+	agentMode     string
+	agentAllow    []string
+	agentDeny     []string
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent [task]",
+	Short: "Lets the model explore and change the codebase with gorani's tools",
+	Long: `Runs a tool-calling agent on the task. The model can list the tree, read files and
+line ranges, grep, summarize packages, write files, run tests and run the commands allowed under
+[runner] in settings.toml until it answers or the step limit is reached. Commands run without a
+shell in the repository root, with a timeout, capped output and no API keys in their environment.
+Read-only tools always run; --mode decides whether write_file, run_tests and run_command
+are refused (read-only), confirmed one by one (ask) or run freely (auto). The task is read
+from stdin when not given as arguments; confirmations are then read from the terminal.
+Every step is recorded under .gorani/agent/.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		task := strings.Join(args, " ")
+		if task == "" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read task from stdin: %v", err)
+			}
+			task = strings.TrimSpace(string(data))
+		}
+		if task == "" {
+			return fmt.Errorf("Usage: agent <task>")
+		}
+
+		policy, err := agent.NewPolicy(agentMode)
+		if err != nil {
+			return err
+		}
+		for _, name := range agentAllow {
+			policy.Set(name, agent.Allow)
+		}
+		for _, name := range agentDeny {
+			policy.Set(name, agent.Deny)
+		}
+
+		opts := agent.Options{
+			Model:    agentModel,
+			MaxSteps: agentMaxSteps,
+			Policy:   policy,
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) && policy.Asks(agent.DefaultTools()) {
+			// Stdin holds the task or is not interactive, so ask on the terminal instead.
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				return fmt.Errorf("cannot ask for confirmation while stdin is not a terminal: pass --mode auto or --mode read-only")
+			}
+			defer tty.Close()
+			opts.In = tty
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		answer, err := agent.Run(ctx, task, opts)
+		if err != nil {
+			return err
+		}
+
+		renderer := prompt.NewMarkdownRenderer(os.Stdout)
+		fmt.Fprintln(renderer, answer)
+		return renderer.Flush()
+	},
+}
+
+func init() {
+	agentCmd.Flags().StringVar(&agentModel, "model", "", "model driving the agent (defaults to settings.toml)")
+	agentCmd.Flags().IntVar(&agentMaxSteps, "max-steps", agent.DefaultMaxSteps, "maximum number of model turns")
+	agentCmd.Flags().StringVar(&agentMode, "mode", agent.ModeAsk, "permission for tools that write or run code: read-only, ask or auto")
+	agentCmd.Flags().StringSliceVar(&agentAllow, "allow", nil, "tools to always allow")
+	agentCmd.Flags().StringSliceVar(&agentDeny, "deny", nil, "tools to always deny")
+	rootCmd.AddCommand(agentCmd)
+}
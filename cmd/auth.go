@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"agent/gorani/internal/auth"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth <login|status|logout>",
+	Short: "Manages the OpenAI API key",
+	Long: `Manages the OpenAI API key used for LLM requests. The key is looked up, in order, in
+OPENAI_API_KEY, a credential helper command (GORANI_CREDENTIAL_HELPER or "helper" under [auth]
+in ~/.config/gorani/settings.toml, e.g. "pass show openai"), ~/.config/gorani/credentials and
+a legacy .env file. A helper in the project settings.toml is ignored, so that a cloned
+repository cannot run commands.
+
+login stores a key in ~/.config/gorani/credentials with 0600 permissions; the key is read
+without echo from the terminal, or from stdin when piped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action := args[0]
+		switch action {
+		case "login":
+			key, err := readAPIKey()
+			if err != nil {
+				return err
+			}
+			path, err := auth.Login(key)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("API key saved to %s.\n", path)
+			return nil
+		case "status":
+			return authStatus()
+		case "logout":
+			removed, err := auth.Logout()
+			if err != nil {
+				return err
+			}
+			if removed {
+				fmt.Println("Stored API key removed.")
+			} else {
+				fmt.Println("No stored API key found.")
+			}
+			return nil
+		default:
+			return fmt.Errorf("Unknown action: %s", action)
+		}
+	},
+}
+
+// readAPIKey reads the key without echo from a terminal, or as a line from piped stdin.
+func readAPIKey() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Print("Enter your OpenAI API key: ")
+		key, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key: %v", err)
+		}
+		return strings.TrimSpace(string(key)), nil
+	}
+
+	key, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && key == "" {
+		return "", fmt.Errorf("failed to read API key: %v", err)
+	}
+	return strings.TrimSpace(key), nil
+}
+
+// authStatus reports where the API key comes from without revealing it.
+func authStatus() error {
+	credential, err := auth.Resolve()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("API key: %s\n", auth.Mask(credential.APIKey))
+	fmt.Printf("Source:  %s\n", credential.Source)
+
+	if path, err := auth.CredentialsPath(); err == nil {
+		if _, statErr := os.Stat(path); statErr == nil {
+			if err := auth.CheckPermissions(path); err != nil {
+				fmt.Println("Warning:", err)
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+}
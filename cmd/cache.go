@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"agent/gorani/internal/cache"
+	"agent/gorani/internal/config"
+	"agent/gorani/internal/prompt"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache <stats|clear>",
+	Short: "Shows or clears the LLM response cache",
+	Long: `Answers to identical structured requests are cached under .gorani/cache, keyed by provider,
+model, schema and messages. The [cache] section of settings.toml sets the TTL and size limit;
+pass --no-cache to any command to bypass it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := prompt.DefaultCache()
+		if store == nil {
+			// Inspecting the cache still works when caching is turned off.
+			store = &cache.Store{Dir: config.StatePath("cache")}
+		}
+
+		action := args[0]
+		switch action {
+		case "stats":
+			stats, err := store.Stats()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Location: %s\n", store.Dir)
+			fmt.Printf("Entries:  %d (%d expired)\n", stats.Entries, stats.Expired)
+			fmt.Printf("Size:     %.1f KB", float64(stats.Bytes)/1024)
+			if store.MaxBytes > 0 {
+				fmt.Printf(" of %d MB", store.MaxBytes>>20)
+			}
+			fmt.Println()
+			if store.TTL > 0 {
+				fmt.Printf("TTL:      %s\n", store.TTL)
+			}
+			if stats.Entries > 0 {
+				fmt.Printf("Oldest:   %s\n", stats.Oldest.Format(time.DateTime))
+				fmt.Printf("Newest:   %s\n", stats.Newest.Format(time.DateTime))
+			}
+			return nil
+		case "clear":
+			removed, err := store.Clear()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d cached answers.\n", removed)
+			return nil
+		default:
+			return fmt.Errorf("Unknown action: %s", action)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	repl "agent/gorani/internal/replbuilder"
+
+	"github.com/spf13/cobra"
+)
+
+var chatModel string
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Starts an interactive multi-turn chat with the model",
+	Long: `Starts a REPL that keeps the conversation history between questions.
+Slash commands attach project context (/grab, /tree, /summary), write answers to disk (/apply),
+switch models (/model), reset (/clear) or save the transcript (/save). Type /help for details.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return repl.Run(cmd.Context(), repl.Options{Model: chatModel})
+	},
+}
+
+func init() {
+	chatCmd.Flags().StringVar(&chatModel, "model", "", "model to chat with (defaults to settings.toml)")
+	rootCmd.AddCommand(chatCmd)
+}
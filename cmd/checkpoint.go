@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"agent/gorani/internal/checkpoint"
+	"agent/gorani/internal/journal"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var checkpointStat bool
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint <list|diff|restore> [checkpoint]",
+	Short: "Lists, diffs and restores the working tree checkpoints taken before gorani writes files",
+	Long: `In a git repository, gorani commits the whole working tree, untracked files included, before
+a command writes its first file. The commits are kept under refs/gorani/checkpoints, so neither
+your index, your branches nor your stash are touched. Checkpoints share their ID with the
+journaled run that followed them (see 'gorani history').
+
+list shows the checkpoints, newest first. diff shows what changed in the working tree since a
+checkpoint, the latest one by default. restore makes the working tree match a checkpoint again,
+removing files created since; it is itself checkpointed and journaled, so 'gorani undo' or
+another restore reverts it.`,
+	Example: `  gorani checkpoint list
+  gorani checkpoint diff --stat
+  gorani checkpoint restore 20241019-153045`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := ""
+		if len(args) == 2 {
+			id = args[1]
+		}
+		switch action := args[0]; action {
+		case "list":
+			return checkpointList()
+		case "diff":
+			c, err := checkpoint.Find(id)
+			if err != nil {
+				return err
+			}
+			text, err := checkpoint.Diff(c, checkpointStat, term.IsTerminal(int(os.Stdout.Fd())))
+			if err != nil {
+				return err
+			}
+			if text == "" {
+				fmt.Printf("No changes since checkpoint %s.\n", c.ID)
+				return nil
+			}
+			fmt.Println(text)
+			return nil
+		case "restore":
+			if id == "" {
+				return fmt.Errorf("name the checkpoint to restore, as listed by 'gorani checkpoint list'")
+			}
+			c, err := checkpoint.Find(id)
+			if err != nil {
+				return err
+			}
+			restored, err := checkpoint.Restore(c)
+			for _, path := range restored {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					fmt.Println("Removed", path)
+				} else {
+					fmt.Println("Restored", path)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if len(restored) == 0 {
+				fmt.Printf("The working tree already matches checkpoint %s.\n", c.ID)
+				return nil
+			}
+			fmt.Printf("Restored checkpoint %s (%s); 'gorani undo' reverts it.\n", c.ID, c.Message)
+			return nil
+		default:
+			return fmt.Errorf("Unknown action: %s", action)
+		}
+	},
+}
+
+func checkpointList() error {
+	checkpoints, err := checkpoint.List()
+	if err != nil {
+		return err
+	}
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints yet.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECKPOINT\tTIME\tCOMMIT\tBEFORE")
+	for _, c := range checkpoints {
+		fmt.Fprintf(w, "%s\t%s\t%.10s\t%s\n", c.ID, c.Time.Format("2006-01-02 15:04"), c.Commit, truncateCommand(c.Message))
+	}
+	return w.Flush()
+}
+
+func init() {
+	// Checkpoint the working tree before the first file of every run is written.
+	journal.OnStart(func(run *journal.Run) error {
+		message := run.Command
+		if message == "" {
+			message = "gorani"
+		}
+		_, err := checkpoint.Create(run.ID, message)
+		return err
+	})
+
+	checkpointCmd.Flags().BoolVar(&checkpointStat, "stat", false, "show only a diffstat")
+	rootCmd.AddCommand(checkpointCmd)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"agent/gorani/internal/apply"
+	"agent/gorani/internal/gooutput"
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/runner"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	editYes     bool
+	editDryRun  bool
+	editNoCheck bool
+	editRetries int
+	editPatch   bool
+
+	editVerify       string
+	editVerifyRounds int
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <task> <file>...",
+	Short: "Asks the model for targeted edits of files and applies them after review",
+	Long: `Sends the task and the files to the model, asking for SEARCH/REPLACE blocks or unified diffs
+instead of whole files. Blocks are matched exactly, then ignoring whitespace, and diff hunks with
+wrong line numbers or a little stale context are applied with fuzz. Edits that still do not match
+are sent back to the model to be regenerated (--retries times); the rest are reported.
+Each changed file is shown as a diff and written once you accept it. With --patch every hunk is
+reviewed on its own, as in git add -p; notes left on hunks (f) are sent back to the model for
+another round of edits.
+
+With --verify, go build, go vet and go test -json run on the given packages after the files are
+written, through the command runner configured under [runner] in settings.toml. Their failures
+are parsed, de-duplicated and sent back with the source around them for up to --verify-rounds
+rounds of fixes.`,
+	Example: `  gorani edit "rename Foo to Bar" internal/foo/foo.go
+  gorani edit -p --verify ./internal/foo/... "fix the off-by-one in Parse" internal/foo/parse.go`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		feedback, verified := "", 0
+		for round := 1; ; round++ {
+			changes, failures, err := apply.RequestEdits(ctx, prompt.DefaultProvider(), apply.Request{
+				Root:     ".",
+				Task:     args[0],
+				Files:    args[1:],
+				Feedback: feedback,
+				Retries:  editRetries,
+				OnAttempt: func(attempt int) {
+					switch {
+					case attempt > 1:
+						fmt.Printf("Some edits did not apply; asking for them again (retry %d of %d)...\n", attempt-1, editRetries)
+					case round > 1:
+						fmt.Println("Asking for a revision...")
+					default:
+						fmt.Println("Requesting edits...")
+					}
+				},
+			})
+			if err != nil {
+				return err
+			}
+			for _, failure := range failures {
+				fmt.Println("✗", failure)
+			}
+			if !editNoCheck {
+				if err := apply.Validate(".", changes); err != nil {
+					return err
+				}
+			}
+
+			opts := apply.ReviewOptions{Yes: editYes, DryRun: editDryRun}
+			var written []*apply.Change
+			var notes []apply.Feedback
+			if editPatch {
+				result, err := apply.ReviewHunks(".", changes, opts)
+				if err != nil {
+					return err
+				}
+				written, notes = result.Written, result.Feedback
+			} else {
+				written, err = apply.Review(".", changes, opts)
+				if err != nil {
+					return err
+				}
+			}
+			if !editDryRun {
+				fmt.Printf("%d of %d files written.\n", len(written), len(changes))
+			}
+			feedback = ""
+			if len(notes) > 0 {
+				feedback = apply.FeedbackText(notes)
+			}
+			if editVerify != "" && len(written) > 0 {
+				report, err := verifyEdits(ctx, editVerify)
+				if err != nil {
+					return err
+				}
+				if report != "" && verified >= editVerifyRounds {
+					return fmt.Errorf("the checks still fail after %d rounds of fixes", editVerifyRounds)
+				}
+				if report != "" {
+					verified++
+					if feedback != "" {
+						feedback += "\n"
+					}
+					feedback += report
+				}
+			}
+			if feedback != "" {
+				// Notes and failed checks start another round with the files as they are now.
+				continue
+			}
+			if len(failures) > 0 {
+				return fmt.Errorf("%d edits could not be applied", len(failures))
+			}
+			return nil
+		}
+	},
+}
+
+func init() {
+	editCmd.Flags().BoolVarP(&editYes, "yes", "y", false, "write every file without asking, skipping files with conflicts or Go syntax errors")
+	editCmd.Flags().BoolVar(&editDryRun, "dry-run", false, "only show the diffs")
+	editCmd.Flags().BoolVar(&editNoCheck, "no-check", false, "write Go files without fixing imports, formatting and type-checking them")
+	editCmd.Flags().IntVar(&editRetries, "retries", 2, "how many times to ask again for edits that did not apply")
+	editCmd.Flags().BoolVarP(&editPatch, "patch", "p", false, "review every hunk on its own, with split, edit and notes for the model")
+	editCmd.Flags().StringVar(&editVerify, "verify", "", "package pattern to build, vet and test after writing, e.g. ./...; failures are sent back to the model")
+	editCmd.Flags().IntVar(&editVerifyRounds, "verify-rounds", 2, "how many times to ask for fixes of failed checks")
+	rootCmd.AddCommand(editCmd)
+}
+
+// verifyEdits builds, vets and tests the packages matching pattern and returns a report of
+// the failures for the model, or "" when every check passed.
+func verifyEdits(ctx context.Context, pattern string) (string, error) {
+	r, err := runner.New()
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Checking %s...\n", pattern)
+	failures, err := gooutput.Verify(ctx, r, pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(failures) == 0 {
+		fmt.Println("✔ Build, vet and tests pass.")
+		return "", nil
+	}
+	for _, f := range failures {
+		fmt.Println("✗", f)
+	}
+	return "go build, go vet or go test failed after the edits:\n\n" + gooutput.Report(r.Dir, failures), nil
+}
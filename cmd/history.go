@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"agent/gorani/internal/journal"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [run]",
+	Short: "Lists the commands that wrote files, or the files of one run",
+	Long: `Lists the journaled runs of gorani, newest first, with the files they wrote.
+Pass a run ID (or a unique prefix) to see its files: M for changed, A for created, D for deleted.
+Use 'gorani undo --run <id>' to restore them.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			run, err := journal.Find(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s  %s  %s%s\n", run.ID, run.Time.Format("2006-01-02 15:04"), run.Command, runStatus(run))
+			for _, e := range run.Entries {
+				status := "M"
+				switch {
+				case e.Before == "":
+					status = "A"
+				case e.After == "":
+					status = "D"
+				}
+				fmt.Printf("  %s %s\n", status, e.Path)
+			}
+			return nil
+		}
+
+		runs, err := journal.Runs()
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			fmt.Println("No files written yet.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN\tTIME\tFILES\tCOMMAND")
+		for i := len(runs) - 1; i >= 0; i-- {
+			run := runs[i]
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s%s\n", run.ID, run.Time.Format("2006-01-02 15:04"), len(run.Entries), truncateCommand(run.Command), runStatus(run))
+		}
+		return w.Flush()
+	},
+}
+
+// runStatus tells whether a run was undone or undid another.
+func runStatus(run *journal.Run) string {
+	switch {
+	case run.UndoneBy != "":
+		return fmt.Sprintf(" (undone by %s)", run.UndoneBy)
+	case run.Undoes != "":
+		return fmt.Sprintf(" (undoes %s)", run.Undoes)
+	}
+	return ""
+}
+
+// truncateCommand shortens long commands, such as edit with its task, for the table.
+func truncateCommand(command string) string {
+	const max = 60
+	if len([]rune(command)) <= max {
+		return command
+	}
+	return string([]rune(command)[:max-1]) + "…"
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
@@ -3,10 +3,14 @@ package cmd
 import (
 	"agent/gorani/internal/implement"
 	"fmt"
+	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 )
 
+var implementSaveArtifacts bool
+
 var implementCmd = &cobra.Command{
 	Use:   "implement <create|merge|prepare> [branchName]",
 	Short: "Manages Git branches (create, merge) or prepares implementation prompt",
@@ -29,7 +33,10 @@ var implementCmd = &cobra.Command{
 		case "prepare":
 			return implement.PrepareImplementPrompt()
 		case "prompt":
-			return implement.Implement()
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			_, err := implement.Implement(ctx, implementSaveArtifacts)
+			return err
 		default:
 			return fmt.Errorf("Unknown action: %s", action)
 		}
@@ -37,5 +44,6 @@ var implementCmd = &cobra.Command{
 }
 
 func init() {
+	implementCmd.Flags().BoolVar(&implementSaveArtifacts, "save-artifacts", false, "save the prompt and answer to input.md and output.md (prompt action)")
 	rootCmd.AddCommand(implementCmd)
 }
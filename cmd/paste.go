@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"agent/gorani/internal/apply"
+	"agent/gorani/internal/templates"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/atotto/clipboard"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	pasteStdin   bool
+	pasteYes     bool
+	pasteDryRun  bool
+	pasteNoCheck bool
+	pastePatch   bool
+)
+
+var pasteCmd = &cobra.Command{
+	Use:   "paste [- | file]",
+	Short: "Writes files from a model answer in the clipboard, stdin or a file",
+	Long: `The inverse of grab: reads an answer from the clipboard, a file such as output.md,
+or stdin ("-", --stdin or piped input), extracts the file edits it contains, shows the diff of
+each file against the working tree and writes the ones you accept.
+
+Understood formats: ">>> path" blocks as produced by grab, fenced code blocks naming their file
+(in the info string, a first-line comment or the line before), SEARCH/REPLACE blocks, unified
+diffs and the JSON code response of 'gorani prompt --json'.
+
+With --patch every hunk is reviewed on its own, as in git add -p. Notes left on hunks (f) are
+copied to the clipboard as a message asking the model for a revision.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromFile := len(args) == 1 && args[0] != "-"
+		fromStdin := !fromFile && (pasteStdin || len(args) == 1 || !term.IsTerminal(int(os.Stdin.Fd())))
+
+		var text string
+		if fromFile {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", args[0], err)
+			}
+			text = string(data)
+		} else if fromStdin {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read stdin: %v", err)
+			}
+			text = string(data)
+		} else {
+			var err error
+			text, err = clipboard.ReadAll()
+			if err != nil {
+				return fmt.Errorf("failed to read the clipboard: %v", err)
+			}
+		}
+
+		edits := apply.ParseAnswer(text)
+		if len(edits) == 0 {
+			return fmt.Errorf("no file edits found: see 'gorani paste --help' for the understood formats")
+		}
+		changes, failures, err := apply.ResolveAll(".", edits)
+		if err != nil {
+			return err
+		}
+		for _, failure := range failures {
+			fmt.Println("✗", failure)
+		}
+		if !pasteNoCheck {
+			if err := apply.Validate(".", changes); err != nil {
+				return err
+			}
+		}
+
+		opts := apply.ReviewOptions{Yes: pasteYes, DryRun: pasteDryRun}
+		if fromStdin && !pasteYes && !pasteDryRun {
+			// Stdin holds the answer, so ask on the terminal instead.
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				return fmt.Errorf("cannot ask for confirmation while reading stdin: pass --yes or --dry-run")
+			}
+			defer tty.Close()
+			opts.In = tty
+		}
+
+		var written []*apply.Change
+		var notes []apply.Feedback
+		if pastePatch {
+			result, err := apply.ReviewHunks(".", changes, opts)
+			if err != nil {
+				return err
+			}
+			written, notes = result.Written, result.Feedback
+		} else {
+			written, err = apply.Review(".", changes, opts)
+			if err != nil {
+				return err
+			}
+		}
+		if !pasteDryRun {
+			fmt.Printf("%d of %d files written.\n", len(written), len(changes))
+		}
+		if len(notes) > 0 {
+			text, err := templates.Render("feedback", templates.Data{Errors: apply.FeedbackText(notes)})
+			if err != nil {
+				return err
+			}
+			if err := clipboard.WriteAll(text); err != nil {
+				fmt.Printf("Failed to copy your notes to the clipboard (%v); send this to the model:\n\n%s", err, text)
+			} else {
+				fmt.Printf("%d notes copied to the clipboard; paste them into the chat for a revision.\n", len(notes))
+			}
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%d edits could not be applied", len(failures))
+		}
+		return nil
+	},
+}
+
+func init() {
+	pasteCmd.Flags().BoolVar(&pasteStdin, "stdin", false, "read the answer from stdin instead of the clipboard")
+	pasteCmd.Flags().BoolVarP(&pasteYes, "yes", "y", false, "write every file without asking, skipping files with conflicts or Go syntax errors")
+	pasteCmd.Flags().BoolVar(&pasteDryRun, "dry-run", false, "only show the diffs")
+	pasteCmd.Flags().BoolVar(&pasteNoCheck, "no-check", false, "write Go files without fixing imports, formatting and type-checking them")
+	pasteCmd.Flags().BoolVarP(&pastePatch, "patch", "p", false, "review every hunk on its own, with split, edit and notes for the model")
+	rootCmd.AddCommand(pasteCmd)
+}
@@ -1,21 +1,40 @@
 package cmd
 
 import (
+	"agent/gorani/internal/editor"
 	"agent/gorani/internal/prompt"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 )
 
+var promptStructured bool
+
 var promptCmd = &cobra.Command{
 	Use:   "prompt",
 	Short: "Prompts OpenAI with user input",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Enter your prompt:")
-		prompt.PromptFromNeovim()
+	Long: `Opens the prompt in your editor and sends it to OpenAI. The editor is the [editor] command
+of the user settings file, $VISUAL or $EDITOR (use "code --wait" for VS Code). A prompt prepared in
+input.md is opened for review; quitting with an empty prompt aborts. The answer is streamed to
+the terminal as it arrives and saved to output.md. Press Ctrl-C to cancel the request.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Ctrl-C cancels the request instead of killing the process.
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		err := prompt.PromptFromEditor(ctx, promptStructured)
+		if errors.Is(err, editor.ErrAborted) {
+			fmt.Println("Prompt not sent:", err)
+			return nil
+		}
+		return err
 	},
 }
 
 func init() {
+	promptCmd.Flags().BoolVar(&promptStructured, "json", false, "request a structured code response instead of streaming Markdown")
 	rootCmd.AddCommand(promptCmd)
 }
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"agent/gorani/internal/editor"
+	"agent/gorani/internal/templates"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var promptsDefault bool
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts <list|show|edit> [name]",
+	Short: "Lists, shows or customizes the prompt templates",
+	Long: `Prompts are text/template files. The built-in ones can be overridden by files of the same
+name in .gorani/prompts/<name>.tmpl. Templates can use these variables:
+
+  {{.Branch}}       current git branch
+  {{.Feature}}      feature name
+  {{.Description}}  feature description
+  {{.Summary}}      summary of the Go symbols
+  {{.Tree}}         directory tree with functions
+  {{.Files}}        selected files, each with .Path and .Content
+  {{.Diff}}         uncommitted changes (git diff HEAD)
+  {{.Errors}}       what went wrong in a previous attempt`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action := args[0]
+		switch action {
+		case "list":
+			infos, err := templates.List()
+			if err != nil {
+				return err
+			}
+			for _, info := range infos {
+				fmt.Printf("%-12s %-8s %s\n", info.Name, info.Source, info.Path)
+			}
+			return nil
+		case "show":
+			if len(args) < 2 {
+				return fmt.Errorf("Usage: prompts show <name> [--default]")
+			}
+			name := args[1]
+			var text, source string
+			var err error
+			if promptsDefault {
+				source = templates.SourceDefault
+				text, err = templates.Default(name)
+			} else {
+				text, source, err = templates.Source(name)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "# %s (%s)\n", name, source)
+			fmt.Print(text)
+			return nil
+		case "edit":
+			if len(args) < 2 {
+				return fmt.Errorf("Usage: prompts edit <name>")
+			}
+			return editTemplate(args[1])
+		default:
+			return fmt.Errorf("Unknown action: %s", action)
+		}
+	},
+}
+
+// editTemplate opens the override of a template in the editor and checks that it still parses.
+func editTemplate(name string) error {
+	path, err := templates.Customize(name)
+	if err != nil {
+		return err
+	}
+
+	if err := editor.Open(path); err != nil {
+		return err
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := templates.Parse(name, string(text)); err != nil {
+		return fmt.Errorf("%v\nFix it with 'gorani prompts edit %s' or delete %s to restore the default", err, name, path)
+	}
+	fmt.Printf("Saved %s.\n", path)
+	return nil
+}
+
+func init() {
+	promptsCmd.Flags().BoolVar(&promptsDefault, "default", false, "show the built-in template instead of the override")
+	rootCmd.AddCommand(promptsCmd)
+}
@@ -1,27 +1,51 @@
 package cmd
 
 import (
+	"agent/gorani/internal/journal"
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/usage"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// noCache sends every LLM request to the model, bypassing the response cache.
+var noCache bool
+
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
 	Use:   "gorani",
 	Short: "Gorani is a CLI tool for code analysis and git branch management",
 	Long: `Gorani provides multiple functionalities such as printing the directory tree,
 grabbing code files, generating documentation, and managing Git branches.`,
+	// Name the command in the usage ledger and the journal of written files.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		usage.SetCommand(cmd.CommandPath())
+		journal.SetCommand(strings.Join(append([]string{cmd.CommandPath()}, args...), " "))
+		if noCache {
+			prompt.DisableCache()
+		}
+	},
 	// If no subcommand is provided, show help.
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "do not reuse cached LLM answers")
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Commands that called the model finish with a summary of their token usage and cost.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if totals := usage.Session(); totals.Requests > 0 {
+		fmt.Printf("Usage: %s\n", totals)
+	}
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
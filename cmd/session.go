@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"agent/gorani/internal/journal"
+	repl "agent/gorani/internal/replbuilder"
+	"agent/gorani/internal/session"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionFormat string
+	sessionOutput string
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   "session <list|show|resume|export|rm> [id]",
+	Short: "Lists, shows, resumes, exports or removes chat sessions",
+	Long: `Chat sessions are stored under .gorani/sessions/<id>.jsonl. Any unique prefix of a
+session id can be used in place of the full id.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action := args[0]
+		switch action {
+		case "list":
+			return listSessions()
+		case "show":
+			if len(args) < 2 {
+				return fmt.Errorf("Usage: session show <id>")
+			}
+			s, err := session.Open(args[1])
+			if err != nil {
+				return err
+			}
+			return s.WriteMarkdown(os.Stdout)
+		case "resume":
+			if len(args) < 2 {
+				return fmt.Errorf("Usage: session resume <id>")
+			}
+			s, err := session.Open(args[1])
+			if err != nil {
+				return err
+			}
+			return repl.Run(cmd.Context(), repl.Options{Session: s})
+		case "export":
+			if len(args) < 2 {
+				return fmt.Errorf("Usage: session export <id> [--format md|json] [-o file]")
+			}
+			return exportSession(args[1])
+		case "rm":
+			if len(args) < 2 {
+				return fmt.Errorf("Usage: session rm <id>...")
+			}
+			for _, id := range args[1:] {
+				if err := session.Remove(id); err != nil {
+					return err
+				}
+				fmt.Printf("Removed session %s.\n", id)
+			}
+			return nil
+		default:
+			return fmt.Errorf("Unknown action: %s", action)
+		}
+	},
+}
+
+// listSessions prints one line per stored session, most recent first.
+func listSessions() error {
+	sessions, err := session.List()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found.")
+		return nil
+	}
+	for _, s := range sessions {
+		fmt.Printf("%s  %s  %-20s %3d msgs %7d tokens  %s\n",
+			s.ID, s.Updated.Format("2006-01-02 15:04"), s.Model, len(s.Messages), s.Usage.TotalTokens, s.Title())
+	}
+	return nil
+}
+
+// exportSession writes a session as Markdown or JSON to stdout or the --output file.
+func exportSession(id string) error {
+	s, err := session.Open(id)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	var buf bytes.Buffer
+	if sessionOutput != "" {
+		w = &buf
+	}
+
+	switch sessionFormat {
+	case "md", "markdown":
+		err = s.WriteMarkdown(w)
+	case "json":
+		err = s.WriteJSON(w)
+	default:
+		return fmt.Errorf("unknown export format %q (use md or json)", sessionFormat)
+	}
+	if err != nil {
+		return err
+	}
+	if sessionOutput != "" {
+		if err := journal.WriteFile(sessionOutput, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", sessionOutput, err)
+		}
+		fmt.Printf("Session %s exported to %s.\n", s.ID, sessionOutput)
+	}
+	return nil
+}
+
+func init() {
+	sessionCmd.Flags().StringVar(&sessionFormat, "format", "md", "export format: md or json")
+	sessionCmd.Flags().StringVarP(&sessionOutput, "output", "o", "", "export to a file instead of stdout")
+	rootCmd.AddCommand(sessionCmd)
+}
@@ -3,20 +3,32 @@ package cmd
 import (
 	"agent/gorani/internal/grab"
 	"fmt"
+	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 )
 
+var smartGrabOpts grab.SmartGrabOptions
+
 var smartGrabCmd = &cobra.Command{
 	Use:   "smartgrab [folder]",
 	Short: "Generates a summary of Go symbols and sends it to OpenAI",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Generates a summary of Go symbols, asks OpenAI which files are needed for a feature
+and grabs them. The feature name defaults to the current git branch and the description
+is read from --feature, --feature-file (use "-" for stdin), piped stdin or the terminal.
+On main or outside git, --yes derives the name from the first words of the description.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		folder := "./"
+		opts := smartGrabOpts
+		opts.Root = "./"
 		if len(args) == 1 {
-			folder = args[0]
+			opts.Root = args[0]
 		}
-		err := grab.SmartGrab(folder)
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		err := grab.SmartGrab(ctx, opts)
 		if err != nil {
 			return fmt.Errorf("smart grab failed: %v", err)
 		}
@@ -25,5 +37,10 @@ var smartGrabCmd = &cobra.Command{
 }
 
 func init() {
+	smartGrabCmd.Flags().StringVar(&smartGrabOpts.Description, "feature", "", "feature description")
+	smartGrabCmd.Flags().StringVar(&smartGrabOpts.DescriptionFile, "feature-file", "", `file containing the feature description ("-" for stdin)`)
+	smartGrabCmd.Flags().StringVar(&smartGrabOpts.Name, "name", "", "feature name (defaults to the current git branch)")
+	smartGrabCmd.Flags().BoolVar(&smartGrabOpts.SaveArtifacts, "save-artifacts", false, "save the prompt and answer to input.md and output.md")
+	smartGrabCmd.Flags().BoolVarP(&smartGrabOpts.Yes, "yes", "y", false, "never prompt; derive a missing name from the description, fail if the description is missing")
 	rootCmd.AddCommand(smartGrabCmd)
 }
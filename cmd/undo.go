@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"agent/gorani/internal/journal"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoRun   string
+	undoForce bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Restores the files written by the last gorani command",
+	Long: `Every file gorani writes is journaled in .gorani/journal with its original content, so a
+command can be undone even outside git. Without --run the latest command that was not undone
+is reverted: files it changed get their previous content back and files it created are removed.
+Files changed since are left alone unless --force is given. The undo is journaled too, so
+undoing it with --run redoes the command.`,
+	Example: `  gorani undo
+  gorani undo --run 20241019-153045
+  gorani history`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var run *journal.Run
+		var err error
+		if undoRun != "" {
+			run, err = journal.Find(undoRun)
+		} else {
+			run, err = journal.Last()
+		}
+		if err != nil {
+			return err
+		}
+
+		restored, err := journal.Undo(run, undoForce)
+		for _, e := range restored {
+			if e.Before == "" {
+				fmt.Println("Removed", e.Path)
+			} else {
+				fmt.Println("Restored", e.Path)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Undid %s (%s).\n", run.ID, run.Command)
+		return nil
+	},
+}
+
+func init() {
+	undoCmd.Flags().StringVar(&undoRun, "run", "", "the run to undo, as listed by 'gorani history' (a unique prefix is enough)")
+	undoCmd.Flags().BoolVar(&undoForce, "force", false, "overwrite files changed since the run")
+	rootCmd.AddCommand(undoCmd)
+}
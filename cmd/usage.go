@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"agent/gorani/internal/usage"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageSince string
+	usageBy    string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Reports token usage and cost of LLM requests",
+	Long: `Every LLM request is recorded in .gorani/usage.jsonl with its command, branch, model,
+tokens, latency and cost. Costs use the [prices] table of settings.toml (dollars per million tokens).
+Answers served from the response cache count as cached requests, with no tokens or cost.`,
+	Example: `  gorani usage
+  gorani usage --since 7d --by model
+  gorani usage --since 2024-10-01 --by branch`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := usage.ParseSince(usageSince, time.Now())
+		if err != nil {
+			return err
+		}
+		records, err := usage.Load(since)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			fmt.Println("No usage recorded.")
+			return nil
+		}
+		groups, err := usage.Summarize(records, usageBy)
+		if err != nil {
+			return err
+		}
+		usage.WriteTable(os.Stdout, usageBy, groups)
+		return nil
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "only include requests newer than a duration (7d, 12h) or date (2024-10-01)")
+	usageCmd.Flags().StringVar(&usageBy, "by", usage.ByCommand, "group by command, model, branch or day")
+	rootCmd.AddCommand(usageCmd)
+}
@@ -3,12 +3,14 @@ module agent/gorani
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/atotto/clipboard v0.1.4
 	github.com/fatih/color v1.18.0
 	github.com/invopop/jsonschema v0.13.0
 	github.com/joho/godotenv v1.5.1
 	github.com/openai/openai-go v0.1.0-alpha.56
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/term v0.24.0
 )
 
 require (
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"agent/gorani/internal/prompt"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// DefaultMaxSteps bounds the number of model turns in a run.
+const DefaultMaxSteps = 20
+
+// systemPrompt explains the agent's job and tools to the model.
+const systemPrompt = `You are a coding agent working inside a software repository.
+Use the available tools to explore the code before answering: list the tree, read files,
+grep and summarize packages. Only change files with write_file when the task requires it,
+and run the tests after changing Go code. Paths are relative to the repository root.
+When you are done, reply with a short summary of what you found or changed.`
+
+// Colored formatters for agent progress.
+var (
+	stepColor   = color.New(color.FgCyan)              // Tool calls in cyan.
+	deniedColor = color.New(color.FgRed)               // Refused calls in red.
+	resultColor = color.New(color.FgHiBlack)           // Tool result summaries dimmed.
+	doneColor   = color.New(color.FgGreen, color.Bold) // Final status in green.
+)
+
+// Options configures an agent run.
+type Options struct {
+	// Model is the model driving the agent. Defaults to settings.toml.
+	Model string
+	// MaxSteps bounds the number of model turns. Defaults to DefaultMaxSteps.
+	MaxSteps int
+	// Policy decides which tools may run. Defaults to ModeAsk.
+	Policy *Policy
+	// Tools are the tools offered to the model. Defaults to DefaultTools.
+	Tools []Tool
+	// Out receives progress output. Defaults to stdout.
+	Out io.Writer
+	// In is read for confirmations. Defaults to stdin.
+	In io.Reader
+	// Confirm is asked before tools with the Ask permission run. Defaults to a y/N prompt on In.
+	Confirm func(tool Tool, args string) bool
+}
+
+// Run lets the model work on task, executing its tool calls until it answers or the step limit is reached.
+// It returns the model's final answer.
+func Run(ctx context.Context, task string, opts Options) (string, error) {
+	if opts.Model == "" {
+		opts.Model = prompt.DefaultModel()
+	}
+	if opts.MaxSteps <= 0 {
+		opts.MaxSteps = DefaultMaxSteps
+	}
+	if opts.Policy == nil {
+		opts.Policy, _ = NewPolicy(ModeAsk)
+	}
+	if opts.Tools == nil {
+		opts.Tools = DefaultTools()
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Confirm == nil {
+		opts.Confirm = confirm(opts.In, opts.Out)
+	}
+
+	tools := make(map[string]Tool, len(opts.Tools))
+	specs := make([]prompt.ToolSpec, 0, len(opts.Tools))
+	for _, tool := range opts.Tools {
+		tools[tool.Name] = tool
+		specs = append(specs, prompt.ToolSpec{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters})
+	}
+
+	transcript, err := NewTranscript()
+	if err != nil {
+		return "", err
+	}
+	defer transcript.Close()
+	resultColor.Fprintf(opts.Out, "Transcript: %s\nPermissions: %s\n", transcript.Path, opts.Policy.Describe(opts.Tools))
+
+	messages := []prompt.Message{
+		{Role: prompt.RoleSystem, Content: systemPrompt},
+		prompt.UserMessage(task),
+	}
+	for _, m := range messages {
+		record(transcript, Event{Message: &m}, opts.Out)
+	}
+
+	for step := 1; step <= opts.MaxSteps; step++ {
+		reply, usage, err := prompt.CompleteWithTools(ctx, opts.Model, messages, specs)
+		if err != nil {
+			record(transcript, Event{Step: step, Error: err.Error()}, opts.Out)
+			return "", fmt.Errorf("step %d: %v", step, err)
+		}
+		messages = append(messages, reply)
+		record(transcript, Event{Step: step, Message: &reply, Usage: &usage}, opts.Out)
+
+		if len(reply.ToolCalls) == 0 {
+			doneColor.Fprintf(opts.Out, "Done after %d steps.\n", step)
+			return reply.Content, nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			result, permission := execute(ctx, tools, opts, call)
+			message := prompt.ToolMessage(call.ID, result)
+			messages = append(messages, message)
+			record(transcript, Event{Step: step, Message: &message, Permission: permission.String()}, opts.Out)
+		}
+	}
+
+	return "", fmt.Errorf("agent stopped after %d steps without a final answer", opts.MaxSteps)
+}
+
+// execute runs a single tool call according to the policy and returns the text sent back to the model.
+func execute(ctx context.Context, tools map[string]Tool, opts Options, call prompt.ToolCall) (string, Permission) {
+	tool, ok := tools[call.Name]
+	if !ok {
+		deniedColor.Fprintf(opts.Out, "✗ unknown tool %s\n", call.Name)
+		return fmt.Sprintf("Error: unknown tool %s.", call.Name), Deny
+	}
+
+	stepColor.Fprintf(opts.Out, "→ %s %s\n", call.Name, abbreviate(call.Arguments, 120))
+	permission := opts.Policy.For(tool)
+	if permission == Ask && opts.Confirm(tool, call.Arguments) {
+		permission = Allow
+	}
+	if permission != Allow {
+		deniedColor.Fprintf(opts.Out, "✗ %s not permitted\n", call.Name)
+		return fmt.Sprintf("Error: the user did not permit %s. Continue without it.", call.Name), Deny
+	}
+
+	result, err := tool.Run(ctx, json.RawMessage(call.Arguments))
+	if err != nil {
+		deniedColor.Fprintf(opts.Out, "✗ %s: %v\n", call.Name, err)
+		return "Error: " + err.Error(), permission
+	}
+	result = truncate(result)
+	resultColor.Fprintf(opts.Out, "  %d bytes returned\n", len(result))
+	return result, permission
+}
+
+// record writes an event to the transcript, warning instead of failing the run.
+func record(transcript *Transcript, e Event, out io.Writer) {
+	if err := transcript.Record(e); err != nil {
+		deniedColor.Fprintln(out, "Warning: transcript not saved:", err)
+	}
+}
+
+// confirm asks the user whether a tool call may run, reading the answer from in.
+func confirm(in io.Reader, out io.Writer) func(tool Tool, args string) bool {
+	reader := bufio.NewReader(in)
+	return func(tool Tool, args string) bool {
+		fmt.Fprintf(out, "Allow %s %s? (y/N): ", tool.Name, abbreviate(args, 400))
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		return response == "y" || response == "yes"
+	}
+}
+
+// abbreviate shortens s to at most n bytes for display.
+func abbreviate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
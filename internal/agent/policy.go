@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission decides whether a tool call may run.
+type Permission int
+
+const (
+	// Allow runs the tool without asking.
+	Allow Permission = iota
+	// Ask asks the user before every call.
+	Ask
+	// Deny refuses the call and tells the model so.
+	Deny
+)
+
+// String returns the name used on the command line.
+func (p Permission) String() string {
+	switch p {
+	case Allow:
+		return "allow"
+	case Ask:
+		return "ask"
+	default:
+		return "deny"
+	}
+}
+
+// Modes selecting the default permission of tools that change the working tree or run code.
+const (
+	ModeReadOnly = "read-only" // only read-only tools run
+	ModeAsk      = "ask"       // other tools ask before every call
+	ModeAuto     = "auto"      // every tool runs without asking
+)
+
+// Policy holds the permission of each tool. Read-only tools are always allowed
+// unless explicitly denied.
+type Policy struct {
+	mode      string
+	overrides map[string]Permission
+}
+
+// NewPolicy returns a policy for the given mode.
+func NewPolicy(mode string) (*Policy, error) {
+	switch mode {
+	case ModeReadOnly, ModeAsk, ModeAuto:
+	default:
+		return nil, fmt.Errorf("unknown mode %q (use %s, %s or %s)", mode, ModeReadOnly, ModeAsk, ModeAuto)
+	}
+	return &Policy{mode: mode, overrides: map[string]Permission{}}, nil
+}
+
+// Set overrides the permission of a single tool.
+func (p *Policy) Set(tool string, permission Permission) {
+	p.overrides[tool] = permission
+}
+
+// For returns the permission of the given tool.
+func (p *Policy) For(tool Tool) Permission {
+	if permission, ok := p.overrides[tool.Name]; ok {
+		return permission
+	}
+	if tool.ReadOnly {
+		return Allow
+	}
+	switch p.mode {
+	case ModeAuto:
+		return Allow
+	case ModeAsk:
+		return Ask
+	default:
+		return Deny
+	}
+}
+
+// Asks reports whether any of the tools needs a confirmation before it runs.
+func (p *Policy) Asks(tools []Tool) bool {
+	for _, tool := range tools {
+		if p.For(tool) == Ask {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe lists the effective permission of every tool.
+func (p *Policy) Describe(tools []Tool) string {
+	var parts []string
+	for _, tool := range tools {
+		parts = append(parts, tool.Name+"="+p.For(tool).String())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,372 @@
+package agent
+
+import (
+	"agent/gorani/internal/apply"
+	"agent/gorani/internal/gocheck"
+	"agent/gorani/internal/gooutput"
+	"agent/gorani/internal/grab"
+	"agent/gorani/internal/journal"
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/runner"
+	"agent/gorani/internal/tree"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Limits keeping tool results small enough for the model's context.
+const (
+	maxReadLines   = 2000
+	maxGrepMatches = 200
+	maxToolOutput  = 32 * 1024
+)
+
+// Tool is a capability the model can invoke.
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments.
+	Parameters interface{}
+	// ReadOnly tools never change the working tree or run project code.
+	ReadOnly bool
+	// Run executes the tool with the raw JSON arguments from the model.
+	Run func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+type pathArgs struct {
+	Path string `json:"path" jsonschema_description:"Path relative to the repository root"`
+}
+
+type readFileArgs struct {
+	Path      string `json:"path" jsonschema_description:"Path relative to the repository root"`
+	StartLine int    `json:"start_line" jsonschema_description:"First line to read, starting at 1 (0 for the beginning)"`
+	EndLine   int    `json:"end_line" jsonschema_description:"Last line to read (0 for the end of the file)"`
+}
+
+type grepArgs struct {
+	Pattern string `json:"pattern" jsonschema_description:"Regular expression (Go syntax) to search for"`
+	Path    string `json:"path" jsonschema_description:"File or folder to search, relative to the repository root"`
+}
+
+type writeFileArgs struct {
+	Path    string `json:"path" jsonschema_description:"Path relative to the repository root"`
+	Content string `json:"content" jsonschema_description:"Complete new content of the file"`
+}
+
+type runTestsArgs struct {
+	Package string `json:"package" jsonschema_description:"Package pattern to test, e.g. ./... or ./internal/grab"`
+}
+
+type runCommandArgs struct {
+	Command []string `json:"command" jsonschema_description:"Program and arguments, e.g. [\"go\", \"vet\", \"./...\"]; run without a shell"`
+}
+
+// DefaultTools returns gorani's built-in repository tools.
+func DefaultTools() []Tool {
+	return []Tool{
+		{
+			Name:        "list_tree",
+			Description: "List the directory tree under a path.",
+			Parameters:  prompt.GenerateSchema[pathArgs](),
+			ReadOnly:    true,
+			Run:         listTree,
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a file, or a range of its lines, with line numbers. Hidden files such as .env cannot be read.",
+			Parameters:  prompt.GenerateSchema[readFileArgs](),
+			ReadOnly:    true,
+			Run:         readFile,
+		},
+		{
+			Name:        "grep",
+			Description: "Search files under a path for a regular expression and list matching lines, skipping hidden files.",
+			Parameters:  prompt.GenerateSchema[grepArgs](),
+			ReadOnly:    true,
+			Run:         grepFiles,
+		},
+		{
+			Name:        "summary",
+			Description: "Summarize the Go functions, structs and interfaces under a path.",
+			Parameters:  prompt.GenerateSchema[pathArgs](),
+			ReadOnly:    true,
+			Run:         summarize,
+		},
+		{
+			Name:        "write_file",
+			Description: "Create or overwrite a file with the given content.",
+			Parameters:  prompt.GenerateSchema[writeFileArgs](),
+			Run:         writeFile,
+		},
+		{
+			Name:        "run_tests",
+			Description: "Run go test for a package pattern and return a summary per package, or the failures with the source around them.",
+			Parameters:  prompt.GenerateSchema[runTestsArgs](),
+			Run:         runTests,
+		},
+		{
+			Name:        "run_command",
+			Description: "Run an allowlisted project command, such as go build or go vet, in the repository root and return its exit code and output.",
+			Parameters:  prompt.GenerateSchema[runCommandArgs](),
+			Run:         runProjectCommand,
+		},
+	}
+}
+
+// resolvePath cleans a model-provided path and refuses paths leaving the working directory.
+func resolvePath(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	cleaned := filepath.Clean(path)
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the repository", path)
+	}
+	return cleaned, nil
+}
+
+func listTree(_ context.Context, raw json.RawMessage) (string, error) {
+	var args pathArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	path, err := resolvePath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	return tree.GenerateTreeString(path, "")
+}
+
+// hidden reports whether a path is or lies in a hidden file or folder, such as .env or .git,
+// which the read-only tools leave alone since they often hold credentials.
+func hidden(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+func readFile(_ context.Context, raw json.RawMessage) (string, error) {
+	var args readFileArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	path, err := resolvePath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	if hidden(path) {
+		return "", fmt.Errorf("refusing to read hidden file %s", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading file %s: %v", path, err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	start, end := args.StartLine, args.EndLine
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		return "", fmt.Errorf("line range %d-%d is outside %s (%d lines)", args.StartLine, args.EndLine, path, len(lines))
+	}
+	if end-start+1 > maxReadLines {
+		end = start + maxReadLines - 1
+	}
+
+	var sb strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&sb, "%5d  %s\n", i, lines[i-1])
+	}
+	if end < len(lines) {
+		fmt.Fprintf(&sb, "... (%d more lines)\n", len(lines)-end)
+	}
+	return sb.String(), nil
+}
+
+func grepFiles(_ context.Context, raw json.RawMessage) (string, error) {
+	var args grepArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	root, err := resolvePath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	re, err := regexp.Compile(args.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	var matches []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip hidden files and folders such as .git.
+		if hidden(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || len(matches) >= maxGrepMatches {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			if re.MatchString(scanner.Text()) {
+				matches = append(matches, fmt.Sprintf("%s:%d: %s", path, line, strings.TrimSpace(scanner.Text())))
+				if len(matches) >= maxGrepMatches {
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "No matches.", nil
+	}
+	result := strings.Join(matches, "\n")
+	if len(matches) >= maxGrepMatches {
+		result += fmt.Sprintf("\n... (stopped after %d matches)", maxGrepMatches)
+	}
+	return result, nil
+}
+
+func summarize(_ context.Context, raw json.RawMessage) (string, error) {
+	var args pathArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	path, err := resolvePath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	return grab.BuildSummary(path)
+}
+
+func writeFile(_ context.Context, raw json.RawMessage) (string, error) {
+	var args writeFileArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	// Writes get the checks of pasted edits, which also keep the model out of .git.
+	path, err := apply.SafePath(".", args.Path)
+	if err != nil {
+		return "", err
+	}
+	content := args.Content
+	var report strings.Builder
+	if strings.HasSuffix(path, ".go") {
+		// Go files are formatted, get their imports fixed and are type-checked first;
+		// the problems found are returned so that the model can fix them.
+		result, err := gocheck.Check(".", map[string]string{path: content})
+		if err != nil {
+			return "", err
+		}
+		for _, d := range result.Diagnostics {
+			fmt.Fprintf(&report, "\n%s", d)
+		}
+		if result.HasSyntaxErrors() {
+			return "", fmt.Errorf("%s not written, it does not parse:%s", path, report.String())
+		}
+		content = result.Files[path]
+		for _, note := range result.Notes[path] {
+			fmt.Fprintf(&report, "\nnote: %s", note)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %v", path, err)
+	}
+	if err := journal.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write file %s: %v", path, err)
+	}
+	return fmt.Sprintf("Wrote %d bytes to %s.%s", len(content), path, report.String()), nil
+}
+
+func runTests(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args runTestsArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	pkg := args.Package
+	if pkg == "" {
+		pkg = "./..."
+	}
+	if strings.HasPrefix(pkg, "-") {
+		return "", fmt.Errorf("invalid package pattern %s", pkg)
+	}
+	return runCommand(ctx, []string{"go", "test", "-json", pkg})
+}
+
+func runProjectCommand(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args runCommandArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	return runCommand(ctx, args.Command)
+}
+
+// runCommand runs an allowlisted command through the sandboxed runner. The output of go build,
+// go vet and go test -json is returned as a report of the failures with the source around
+// them rather than raw. A failing command is a result for the model, not a tool failure.
+func runCommand(ctx context.Context, command []string) (string, error) {
+	if len(command) == 0 {
+		return "", fmt.Errorf("no command given: pass the program and its arguments, e.g. [\"go\", \"vet\", \"./...\"]")
+	}
+	r, err := runner.New()
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(command, "-json") {
+		// The JSON events are parsed, not shown, and far more verbose.
+		r.MaxOutput = max(r.MaxOutput, gooutput.TestOutputCap)
+	}
+	result, err := r.Run(ctx, command...)
+	if err != nil {
+		return "", err
+	}
+	failures, parsed := gooutput.Parse(r.Dir, result)
+	switch {
+	case !parsed:
+		return result.String(), nil
+	case len(failures) > 0:
+		return fmt.Sprintf("$ %s\nfailed after %s with %d problems:\n\n%s", strings.Join(command, " "), result.Duration.Round(time.Millisecond), len(failures), gooutput.Report(r.Dir, failures)), nil
+	case len(command) > 1 && command[1] == "test":
+		return gooutput.ParseTest(r.Dir, result.Stdout, result.Stderr).Summary, nil
+	}
+	return result.String(), nil
+}
+
+// truncate caps tool output, keeping the end where errors usually are.
+func truncate(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	return "... (output truncated)\n" + s[len(s)-maxToolOutput:]
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileRefusesUnsafePaths(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	for _, path := range []string{".git/config", ".git/hooks/pre-commit", "./.git/HEAD", "../outside.txt", "/tmp/abs.txt", ""} {
+		raw, _ := json.Marshal(writeFileArgs{Path: path, Content: "x\n"})
+		if _, err := writeFile(context.Background(), raw); err == nil {
+			t.Errorf("write_file %q succeeded", path)
+		}
+	}
+	if _, err := os.Stat(".git"); !os.IsNotExist(err) {
+		t.Errorf(".git was created: %v", err)
+	}
+
+	raw, _ := json.Marshal(writeFileArgs{Path: "notes/todo.txt", Content: "x\n"})
+	if _, err := writeFile(context.Background(), raw); err != nil {
+		t.Errorf("write_file notes/todo.txt: %v", err)
+	}
+}
+
+func TestReadFileSkipsHiddenFiles(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	for _, dir := range []string{".git", "notes"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{".env", ".git/config", "notes/todo.txt"} {
+		if err := os.WriteFile(path, []byte("secret=1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{".env", ".git/config", "./.git/config"} {
+		raw, _ := json.Marshal(readFileArgs{Path: path})
+		if _, err := readFile(context.Background(), raw); err == nil {
+			t.Errorf("read_file %q succeeded", path)
+		}
+	}
+	raw, _ := json.Marshal(readFileArgs{Path: "notes/todo.txt"})
+	if _, err := readFile(context.Background(), raw); err != nil {
+		t.Errorf("read_file notes/todo.txt: %v", err)
+	}
+	raw, _ = json.Marshal(grepArgs{Pattern: "secret"})
+	if got, err := grepFiles(context.Background(), raw); err != nil || strings.Contains(got, ".env") || strings.Contains(got, ".git") {
+		t.Errorf("grep = %q, %v", got, err)
+	}
+}
+
+func TestRunCommandWithoutArguments(t *testing.T) {
+	for _, command := range [][]string{nil, {}} {
+		if _, err := runCommand(context.Background(), command); err == nil {
+			t.Errorf("runCommand(%q) succeeded", command)
+		}
+	}
+}
+
+func TestNewTranscriptIsUnique(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	first, err := NewTranscript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := NewTranscript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if first.Path == second.Path {
+		t.Errorf("two runs share the transcript %s", first.Path)
+	}
+}
+
+func TestPolicyAsks(t *testing.T) {
+	tools := DefaultTools()
+	for mode, want := range map[string]bool{ModeAsk: true, ModeAuto: false, ModeReadOnly: false} {
+		policy, err := NewPolicy(mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := policy.Asks(tools); got != want {
+			t.Errorf("mode %s: Asks = %v, want %v", mode, got, want)
+		}
+	}
+
+	policy, _ := NewPolicy(ModeAsk)
+	for _, tool := range tools {
+		if !tool.ReadOnly {
+			policy.Set(tool.Name, Allow)
+		}
+	}
+	if policy.Asks(tools) {
+		t.Error("Asks = true with every writing tool allowed")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	ask := confirm(strings.NewReader("y\nno\nYES\n"), io.Discard)
+	tool := Tool{Name: "write_file"}
+	var got []bool
+	for range 4 {
+		got = append(got, ask(tool, "{}"))
+	}
+	// The last question meets the end of the input, which denies.
+	if want := []bool{true, false, true, false}; !slices.Equal(got, want) {
+		t.Errorf("answers = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"agent/gorani/internal/config"
+	"agent/gorani/internal/prompt"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Event is one line of an agent transcript.
+type Event struct {
+	Time       time.Time       `json:"time"`
+	Step       int             `json:"step"`
+	Message    *prompt.Message `json:"message,omitempty"`
+	Permission string          `json:"permission,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Usage      *prompt.Usage   `json:"usage,omitempty"`
+}
+
+// Transcript appends agent events to .gorani/agent/<id>.jsonl.
+type Transcript struct {
+	Path string
+	file *os.File
+}
+
+// NewTranscript creates a transcript file for a new run. Its id is the start time with a random
+// suffix, like session ids, so that runs started in the same second get their own file.
+func NewTranscript() (*Transcript, error) {
+	dir := config.StatePath("agent")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate transcript id: %v", err)
+	}
+	path := filepath.Join(dir, time.Now().Format("20060102-150405")+"-"+hex.EncodeToString(suffix)+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcript %s: %v", path, err)
+	}
+	return &Transcript{Path: path, file: file}, nil
+}
+
+// Record appends an event.
+func (t *Transcript) Record(e Event) error {
+	e.Time = time.Now()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = t.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the transcript file.
+func (t *Transcript) Close() error {
+	return t.file.Close()
+}
@@ -0,0 +1,303 @@
+package apply
+
+import (
+	"agent/gorani/internal/diff"
+	"agent/gorani/internal/gocheck"
+	"agent/gorani/internal/journal"
+	"agent/gorani/internal/snapshot"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kind is the form of an edit.
+type Kind int
+
+const (
+	// KindWrite replaces the whole file with Content.
+	KindWrite Kind = iota
+	// KindReplace substitutes Search with Replace.
+	KindReplace
+	// KindPatch applies the Hunks of a unified diff.
+	KindPatch
+)
+
+// Edit is a change to a single file proposed by the model or pasted by the user.
+type Edit struct {
+	Kind Kind
+	// Path is relative to the project root.
+	Path string
+	// Content is the complete new file of a KindWrite edit.
+	Content string
+	// Search and Replace are the text of a KindReplace edit. An empty Search creates the file.
+	Search  string
+	Replace string
+	// Hunks are the changes of a KindPatch edit.
+	Hunks []diff.Hunk
+	// Base is the hash, or a prefix of it, of the snapshot the edit was made against: the file as
+	// it was grabbed, named by a ">>> path (base <hash>)" header or set by RequestEdits. When
+	// empty it is taken from the latest snapshot of the file.
+	Base string
+}
+
+// Describe names the edit for reports, e.g. "search/replace in main.go".
+func (e Edit) Describe() string {
+	switch e.Kind {
+	case KindReplace:
+		return "search/replace in " + e.Path
+	case KindPatch:
+		if len(e.Hunks) == 1 {
+			return fmt.Sprintf("hunk %s of %s", e.Hunks[0].Header(), e.Path)
+		}
+		return fmt.Sprintf("patch of %s (%d hunks)", e.Path, len(e.Hunks))
+	}
+	return "new content of " + e.Path
+}
+
+// Format renders the edit the way a model writes it: a SEARCH/REPLACE block, a diff or a ">>> path" block.
+func (e Edit) Format() string {
+	var sb strings.Builder
+	switch e.Kind {
+	case KindReplace:
+		fmt.Fprintf(&sb, "%s\n<<<<<<< SEARCH\n%s=======\n%s>>>>>>> REPLACE\n", e.Path, e.Search, e.Replace)
+	case KindPatch:
+		fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", e.Path, e.Path)
+		for _, h := range e.Hunks {
+			sb.WriteString(h.Header() + "\n")
+			for _, l := range h.Lines {
+				sb.WriteString(diff.Prefix(l.Kind) + l.Text + "\n")
+			}
+		}
+	default:
+		fmt.Fprintf(&sb, ">>> %s\n%s", e.Path, e.Content)
+	}
+	return sb.String()
+}
+
+// applyTo returns text with the edit applied, notes on edits that only matched loosely,
+// and the parts of the edit that failed. A patch applies the hunks that match even when others fail.
+func (e Edit) applyTo(text string, exists bool) (string, []string, []*Failure) {
+	switch e.Kind {
+	case KindReplace:
+		if e.Search == "" {
+			if exists && strings.TrimSpace(text) != "" {
+				return text, nil, []*Failure{{Edit: e, Err: fmt.Errorf("empty search text, but the file already exists")}}
+			}
+			return e.Replace, nil, nil
+		}
+		updated, level, err := diff.ReplaceBlock(text, e.Search, e.Replace)
+		if err != nil {
+			return text, nil, []*Failure{{Edit: e, Err: err}}
+		}
+		var notes []string
+		if level != diff.Exact {
+			notes = append(notes, fmt.Sprintf("search text matched %s", level))
+		}
+		return updated, notes, nil
+	case KindPatch:
+		updated, results := diff.ApplyHunks(text, e.Hunks)
+		var notes []string
+		var failures []*Failure
+		for _, r := range results {
+			if r.Err != nil {
+				failed := e
+				failed.Hunks = []diff.Hunk{r.Hunk}
+				failures = append(failures, &Failure{Edit: failed, Err: r.Err})
+				continue
+			}
+			if r.Level != diff.Exact || r.Line != r.Hunk.OldStart {
+				notes = append(notes, fmt.Sprintf("%s applied %s at line %d", r.Hunk.Header(), r.Level, r.Line))
+			}
+		}
+		return updated, notes, failures
+	}
+	return e.Content, nil, nil
+}
+
+// Change is the combined effect of the edits of one file on the working tree.
+type Change struct {
+	Path string
+	// Edits are the edits that produced New.
+	Edits []Edit
+	// Old is the current content, empty for new files.
+	Old string
+	// New is the content after the edits.
+	New string
+	// Exists reports whether the file is already present.
+	Exists bool
+	// Notes describe edits that matched only loosely, e.g. ignoring whitespace, and fixes made by Validate.
+	Notes []string
+	// Diagnostics are the Go syntax and type errors found by Validate.
+	Diagnostics []gocheck.Diagnostic
+	// Stale is set when the file changed since it was grabbed. The edits are then applied to
+	// Base, the grabbed content, and merged with Old; Conflicts counts the regions left with
+	// conflict markers because both sides changed them.
+	Stale     bool
+	Base      string
+	Conflicts int
+	// theirs is Base with the edits applied.
+	theirs string
+}
+
+// Failure is an edit that could not be applied.
+type Failure struct {
+	Edit Edit
+	Err  error
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("%s: %v", f.Edit.Describe(), f.Err)
+}
+
+// Diff returns the unified diff of the change.
+func (c *Change) Diff() string {
+	oldName := "a/" + c.Path
+	if !c.Exists {
+		oldName = "/dev/null"
+	}
+	return diff.Unified(oldName, "b/"+c.Path, c.Old, c.New)
+}
+
+// Unchanged reports whether applying the change would leave the file as it is.
+func (c *Change) Unchanged() bool {
+	return c.Exists && c.Old == c.New
+}
+
+// HasSyntaxErrors reports whether Validate found Go syntax errors in the change.
+func (c *Change) HasSyntaxErrors() bool {
+	for _, d := range c.Diagnostics {
+		if d.Syntax && d.Path == c.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve reads the current content of the edited file under root and applies the edit to it.
+func Resolve(root string, e Edit) (*Change, error) {
+	changes, failures, err := ResolveAll(root, []Edit{e})
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		return nil, failures[0]
+	}
+	return changes[0], nil
+}
+
+// ResolveAll groups edits by file, in the order the files first appear, and applies each file's
+// edits in turn to its current content. Edits that do not apply are reported as failures and
+// skipped; the other edits of the file still apply. Files whose edits all failed are left out.
+// When a file changed since it was grabbed, its edits are applied to the grabbed content and
+// merged three-way with the current content, so that changes made meanwhile are kept.
+func ResolveAll(root string, edits []Edit) ([]*Change, []*Failure, error) {
+	return resolve(root, nil, edits)
+}
+
+// resolve applies edits on top of earlier changes, reading files that have no change yet.
+func resolve(root string, earlier []*Change, edits []Edit) ([]*Change, []*Failure, error) {
+	changes := append([]*Change(nil), earlier...)
+	index := map[string]*Change{}
+	for _, c := range changes {
+		index[c.Path] = c
+	}
+	var failures []*Failure
+	for _, e := range edits {
+		c, ok := index[e.Path]
+		if !ok {
+			path, err := SafePath(root, e.Path)
+			if err != nil {
+				return nil, nil, err
+			}
+			c = &Change{Path: e.Path}
+			data, err := os.ReadFile(path)
+			switch {
+			case err == nil:
+				c.Old = string(data)
+				c.Exists = true
+			case !os.IsNotExist(err):
+				return nil, nil, fmt.Errorf("failed to read %s: %v", e.Path, err)
+			}
+			c.New = c.Old
+			base := ""
+			if e.Base != "" {
+				base, _ = snapshot.Expand(e.Base)
+			} else {
+				base, _ = snapshot.Lookup(e.Path)
+			}
+			// Content gorani wrote itself, such as an earlier answer, is not a change of the user's.
+			if current := journal.Hash([]byte(c.Old)); c.Exists && base != "" && base != current && journal.LastWrite(path) != current {
+				if content, err := snapshot.Content(base); err == nil {
+					c.Stale, c.Base, c.theirs = true, string(content), string(content)
+				}
+			}
+			index[e.Path] = c
+			changes = append(changes, c)
+		}
+
+		current := c.New
+		if c.Stale {
+			current = c.theirs
+		}
+		updated, notes, failed := e.applyTo(current, c.Exists || len(c.Edits) > 0)
+		failures = append(failures, failed...)
+		if updated == current && len(failed) > 0 {
+			continue
+		}
+		if c.Stale {
+			c.theirs = updated
+			c.New, c.Conflicts = diff.Merge3(c.Base, c.Old, c.theirs)
+		} else {
+			c.New = updated
+		}
+		c.Notes = append(c.Notes, notes...)
+		c.Edits = append(c.Edits, e)
+	}
+
+	result := changes[:0]
+	for _, c := range changes {
+		if len(c.Edits) > 0 {
+			result = append(result, c)
+		}
+	}
+	return result, failures, nil
+}
+
+// Write saves the new content of the change, creating missing directories.
+// Existing files keep their permissions.
+func Write(root string, c *Change) error {
+	path, err := SafePath(root, c.Path)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := journal.WriteFile(path, []byte(c.New), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %v", c.Path, err)
+	}
+	// The written content is the base of the next answer about the file. Without a snapshot
+	// later edits are only merged less precisely, so a failure is not an error.
+	snapshot.Record(path, []byte(c.New))
+	return nil
+}
+
+// SafePath joins a relative path onto root, refusing absolute paths and paths leaving root.
+func SafePath(root, path string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimSpace(path)))
+	if path == "" || clean == "." {
+		return "", fmt.Errorf("empty file path")
+	}
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to write %s: path is outside the project", path)
+	}
+	if clean == ".git" || strings.HasPrefix(clean, ".git"+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to write %s: path is inside .git", path)
+	}
+	return filepath.Join(root, clean), nil
+}
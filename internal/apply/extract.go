@@ -0,0 +1,246 @@
+package apply
+
+import (
+	"agent/gorani/internal/diff"
+	"agent/gorani/internal/prompt"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SEARCH/REPLACE block markers, as in:
+//
+//	main.go
+//	<<<<<<< SEARCH
+//	old lines
+//	=======
+//	new lines
+//	>>>>>>> REPLACE
+var (
+	searchStart   = regexp.MustCompile(`^<{5,9} ?SEARCH\s*$`)
+	searchDivider = regexp.MustCompile(`^={5,9}\s*$`)
+	replaceEnd    = regexp.MustCompile(`^>{5,9} ?REPLACE\s*$`)
+)
+
+// fenceAttr matches path attributes of a code fence info string, e.g. title="main.go" or file=main.go.
+var fenceAttr = regexp.MustCompile(`(?:title|file|filename|path)=["']?([^"'\s]+)["']?`)
+
+// fileComment matches a first line naming the file, e.g. "// file: main.go", "# path: x.py" or "<!-- x.html -->".
+var fileComment = regexp.MustCompile(`^\s*(?://|#|--|;|/\*|<!--)\s*(?:(?i:file(?:name)?|path)\s*:\s*)?(\S+?)\s*(?:\*/|-->)?\s*$`)
+
+// ParseAnswer extracts file edits from a model answer. A CodeResponse JSON answer becomes a
+// single file write; anything else is read as Markdown:
+//
+//   - ">>> path" blocks, as produced by grab
+//   - fenced code blocks whose info string names the file (```go main.go, ```go title="main.go"),
+//     whose first line is a comment naming it (// file: main.go), or that follow a line naming it
+//     (a heading, **main.go**, File: main.go, or a sentence ending in `main.go`:)
+//   - SEARCH/REPLACE blocks following a line naming the file
+//   - unified diffs, fenced or not
+func ParseAnswer(text string) []Edit {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if edits, ok := parseCodeResponse(text); ok {
+		return edits
+	}
+	return parseMarkdown(strings.Split(text, "\n"), "")
+}
+
+// parseCodeResponse reads the strict JSON answer of the structured prompts.
+func parseCodeResponse(text string) ([]Edit, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+	var resp prompt.CodeResponse
+	if err := json.Unmarshal([]byte(trimmed), &resp); err != nil || resp.Filename == "" || len(resp.Scripts) == 0 {
+		return nil, false
+	}
+	return []Edit{{Kind: KindWrite, Path: resp.Filename, Content: resp.Scripts[0]}}, true
+}
+
+// parseMarkdown scans lines for edits; hint is the file named just before them, if any.
+func parseMarkdown(lines []string, hint string) []Edit {
+	var edits []Edit
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if path, ok := headerPath(line); ok {
+			end := blockEnd(lines, i+1)
+			edits = append(edits, Edit{Kind: KindWrite, Path: path, Content: blockContent(lines[i+1 : end]), Base: headerBase(line)})
+			hint = ""
+			i = end
+			continue
+		}
+
+		if searchStart.MatchString(strings.TrimSpace(line)) {
+			edit, end, ok := parseSearchReplace(lines, i, hint)
+			if ok {
+				edits = append(edits, edit)
+			}
+			// The hint stays so that several blocks can follow one file name.
+			i = end
+			continue
+		}
+
+		if diff.IsDiffStart(lines, i) {
+			files, n := diff.ParseUnified(lines[i:])
+			edits = append(edits, patchEdits(files)...)
+			hint = ""
+			i += max(n, 1)
+			continue
+		}
+
+		if fence, info, ok := openingFence(line); ok {
+			end := closingFence(lines, i+1, fence)
+			body := lines[i+1 : end]
+			path := fencePath(info)
+			if path == "" {
+				path = hint
+			}
+
+			if containsEdits(body) {
+				edits = append(edits, parseMarkdown(body, path)...)
+			} else if len(body) > 0 {
+				// A first line naming the file is dropped, unless the fence names another file:
+				// then it is content, such as "#!/bin/bash".
+				if named := commentPath(body[0]); named != "" {
+					if fencePath(info) == "" {
+						path, body = named, body[1:]
+					} else if filepath.Clean(named) == filepath.Clean(path) {
+						body = body[1:]
+					}
+				}
+				if path != "" {
+					edits = append(edits, Edit{Kind: KindWrite, Path: path, Content: joinContent(body)})
+				}
+			}
+			hint = ""
+			i = end + 1
+			continue
+		}
+
+		if named := hintPath(line); named != "" {
+			hint = named
+		} else if strings.TrimSpace(line) != "" {
+			hint = ""
+		}
+		i++
+	}
+	return edits
+}
+
+// parseSearchReplace reads the SEARCH/REPLACE block starting at lines[start].
+// It returns the index after the block and whether the block was complete.
+func parseSearchReplace(lines []string, start int, path string) (Edit, int, bool) {
+	divider := -1
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case divider < 0 && searchDivider.MatchString(trimmed):
+			divider = i
+		case divider >= 0 && replaceEnd.MatchString(trimmed):
+			if path == "" {
+				return Edit{}, i + 1, false
+			}
+			return Edit{
+				Kind:    KindReplace,
+				Path:    path,
+				Search:  joinBlock(lines[start+1 : divider]),
+				Replace: joinBlock(lines[divider+1 : i]),
+			}, i + 1, true
+		}
+	}
+	return Edit{}, len(lines), false
+}
+
+// joinBlock joins the lines of a SEARCH or REPLACE section, each ending with a newline.
+func joinBlock(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// patchEdits turns the file diffs of a unified diff into edits. New files become writes;
+// deletions are not supported and are skipped.
+func patchEdits(files []diff.FileDiff) []Edit {
+	var edits []Edit
+	for _, f := range files {
+		switch {
+		case f.IsDelete():
+			continue
+		case f.IsNew():
+			var content []string
+			for _, h := range f.Hunks {
+				content = append(content, h.New()...)
+			}
+			edits = append(edits, Edit{Kind: KindWrite, Path: f.Path(), Content: joinContent(content)})
+		case len(f.Hunks) > 0:
+			edits = append(edits, Edit{Kind: KindPatch, Path: f.Path(), Hunks: f.Hunks})
+		}
+	}
+	return edits
+}
+
+// containsEdits reports whether a code block holds SEARCH/REPLACE blocks or a diff instead of code.
+func containsEdits(lines []string) bool {
+	for i, line := range lines {
+		if searchStart.MatchString(strings.TrimSpace(line)) || diff.IsDiffStart(lines, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// fencePath finds the file named by a code fence info string.
+func fencePath(info string) string {
+	if m := fenceAttr.FindStringSubmatch(info); m != nil {
+		return m[1]
+	}
+	return infoPath(info)
+}
+
+// commentPath returns the file named by a comment line such as "// file: main.go" or "# main.py".
+func commentPath(line string) string {
+	m := fileComment.FindStringSubmatch(line)
+	if m == nil || !looksLikePath(m[1]) {
+		return ""
+	}
+	return strings.Trim(m[1], "`")
+}
+
+// hintPath returns the file named by a line introducing a code block: a heading, a bold or
+// backticked path, "File: path", a bare path, or a sentence ending with a backticked path and a colon.
+func hintPath(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || len(trimmed) > 200 {
+		return ""
+	}
+
+	bare := strings.TrimLeft(trimmed, "#")
+	bare = strings.TrimSpace(bare)
+	bare = strings.TrimRight(bare, ":")
+	for _, label := range []string{"File:", "file:", "Filename:", "Path:"} {
+		if rest, ok := strings.CutPrefix(bare, label); ok {
+			bare = rest
+		}
+	}
+	bare = strings.Trim(strings.TrimSpace(bare), "*`_\"'")
+	bare = strings.TrimRight(bare, ":")
+	if !strings.ContainsAny(bare, " \t") && looksLikePath(bare) {
+		return bare
+	}
+
+	// "Here is the updated `main.go`:"
+	if strings.HasSuffix(trimmed, ":") {
+		parts := strings.Split(trimmed, "`")
+		if len(parts) >= 3 {
+			candidate := parts[len(parts)-2]
+			if !strings.ContainsAny(candidate, " \t") && looksLikePath(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
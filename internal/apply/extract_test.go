@@ -0,0 +1,163 @@
+package apply
+
+import (
+	"agent/gorani/internal/diff"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// summary renders an edit compactly for comparison: its kind, path and base, then its content,
+// search and replace sections or hunks.
+func summary(e Edit) string {
+	var sb strings.Builder
+	switch e.Kind {
+	case KindWrite:
+		fmt.Fprintf(&sb, "write %s", e.Path)
+		if e.Base != "" {
+			fmt.Fprintf(&sb, " base %s", e.Base)
+		}
+		fmt.Fprintf(&sb, "\n%s", e.Content)
+	case KindReplace:
+		fmt.Fprintf(&sb, "replace %s\n%s=======\n%s", e.Path, e.Search, e.Replace)
+	case KindPatch:
+		fmt.Fprintf(&sb, "patch %s\n", e.Path)
+		for _, h := range e.Hunks {
+			sb.WriteString(h.Header() + "\n")
+			for _, l := range h.Lines {
+				sb.WriteString(diff.Prefix(l.Kind) + l.Text + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func TestParseAnswer(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   []string
+	}{
+		{
+			name:   "grab blocks",
+			answer: ">>> main.go (base 0123456789ab)\npackage main\n\n---\n>>> util/util.go\npackage util\n",
+			want:   []string{"write main.go base 0123456789ab\npackage main\n", "write util/util.go\npackage util\n"},
+		},
+		{
+			name:   "grab block with a fenced body and prose after it",
+			answer: ">>> main.go\n```go\npackage main\n```\nThis adds the package clause.\n",
+			want:   []string{"write main.go\npackage main\n"},
+		},
+		{
+			name:   "fence naming the file",
+			answer: "Here you go:\n\n```go main.go\npackage main\n```\n",
+			want:   []string{"write main.go\npackage main\n"},
+		},
+		{
+			name:   "fence with a title attribute",
+			answer: "```go title=\"cmd/root.go\"\npackage cmd\n```\n",
+			want:   []string{"write cmd/root.go\npackage cmd\n"},
+		},
+		{
+			name:   "file comment on the first line",
+			answer: "```go\n// file: store/store.go\npackage store\n```\n",
+			want:   []string{"write store/store.go\npackage store\n"},
+		},
+		{
+			name:   "fence naming the file keeps a shebang",
+			answer: "```bash deploy.sh\n#!/bin/bash\necho hi\n```\n",
+			want:   []string{"write deploy.sh\n#!/bin/bash\necho hi\n"},
+		},
+		{
+			name:   "fence and file comment naming the same file",
+			answer: "```go store/store.go\n// store/store.go\npackage store\n```\n",
+			want:   []string{"write store/store.go\npackage store\n"},
+		},
+		{
+			name:   "bold path before the fence",
+			answer: "**store/store.go**\n```go\npackage store\n```\n",
+			want:   []string{"write store/store.go\npackage store\n"},
+		},
+		{
+			name:   "sentence ending in a path",
+			answer: "Here is the updated `main.go`:\n\n```go\npackage main\n```\n",
+			want:   []string{"write main.go\npackage main\n"},
+		},
+		{
+			name:   "fence without a path is not an edit",
+			answer: "For example:\n\n```go\nfmt.Println(1)\n```\n",
+		},
+		{
+			name:   "search/replace blocks sharing a path",
+			answer: "main.go\n<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n\n<<<<<<< SEARCH\nc\n=======\n>>>>>>> REPLACE\n",
+			want:   []string{"replace main.go\na\n=======\nb\n", "replace main.go\nc\n=======\n"},
+		},
+		{
+			name:   "fenced search/replace",
+			answer: "```go\nmain.go\n<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n```\n",
+			want:   []string{"replace main.go\na\n=======\nb\n"},
+		},
+		{
+			name:   "search/replace without a path is dropped",
+			answer: "<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n",
+		},
+		{
+			name:   "unified diff",
+			answer: "```diff\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n package main\n-var x = 1\n+var x = 2\n```\n",
+			want:   []string{"patch main.go\n@@ -1,2 +1,2 @@\n package main\n-var x = 1\n+var x = 2\n"},
+		},
+		{
+			name:   "diff removing a line starting with --",
+			answer: "--- a/q.sql\n+++ b/q.sql\n@@ -1,2 +1,2 @@\n--- old\n+++ new\n select 1;\n",
+			want:   []string{"patch q.sql\n@@ -1,2 +1,2 @@\n--- old\n+++ new\n select 1;\n"},
+		},
+		{
+			name:   "diff creating and deleting files",
+			answer: "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+package p\n--- a/old.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package p\n",
+			want:   []string{"write new.go\npackage p\n"},
+		},
+		{
+			name:   "JSON code response",
+			answer: `{"filename": "main.go", "scripts": ["package main\n"]}`,
+			want:   []string{"write main.go\npackage main\n"},
+		},
+		{
+			name:   "CRLF line endings",
+			answer: "main.go\r\n<<<<<<< SEARCH\r\na\r\n=======\r\nb\r\n>>>>>>> REPLACE\r\n",
+			want:   []string{"replace main.go\na\n=======\nb\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range ParseAnswer(tt.answer) {
+				got = append(got, summary(e))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAnswer =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyToReportsFailedHunks(t *testing.T) {
+	edits := ParseAnswer("--- a/m.go\n+++ b/m.go\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n@@ -4,2 +4,2 @@\n d\n-x\n+X\n")
+	if len(edits) != 1 {
+		t.Fatalf("got %d edits, want 1", len(edits))
+	}
+	got, _, failures := edits[0].applyTo("a\nb\nc\nd\ne\n", true)
+	if got != "a\nB\nc\nd\ne\n" {
+		t.Errorf("applied %q, want the first hunk only", got)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("got %d failures, want 1", len(failures))
+	}
+	f := failures[0]
+	if len(f.Edit.Hunks) != 1 || f.Edit.Hunks[0].OldStart != 4 {
+		t.Errorf("failed edit = %s, want the second hunk only", f.Edit.Describe())
+	}
+	if want := "@@ -4,2 +4,2 @@ does not match the file; closest match at line 4 (1 of 2 lines)"; f.Err.Error() != want {
+		t.Errorf("err = %q, want %q", f.Err, want)
+	}
+}
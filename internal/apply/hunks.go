@@ -0,0 +1,387 @@
+package apply
+
+import (
+	"agent/gorani/internal/diff"
+	"agent/gorani/internal/editor"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Feedback is a note left on a hunk during review, to be sent back to the model.
+type Feedback struct {
+	Path string
+	// Hunk is the hunk the note is about, in unified diff form.
+	Hunk string
+	Note string
+}
+
+// ReviewResult is the outcome of ReviewHunks.
+type ReviewResult struct {
+	// Written are the changes written, with New holding only the accepted hunks.
+	Written  []*Change
+	Feedback []Feedback
+}
+
+// hunkHelp explains the answers of ReviewHunks, like git add -p.
+const hunkHelp = `y - apply this hunk
+n - do not apply this hunk
+a - apply this hunk and all later hunks in the file
+d - do not apply this hunk or any of the later hunks in the file
+s - split the current hunk into smaller hunks
+e - manually edit the current hunk
+f - leave a note on this hunk for the model
+q - quit; do not apply this hunk or any of the remaining ones
+? - print help`
+
+// ReviewHunks shows every change hunk by hunk and writes each file with the hunks the user
+// accepts. Notes left on hunks are returned as feedback for a revision by the model.
+// With Yes or DryRun there is nothing to choose, and it behaves like Review.
+func ReviewHunks(root string, changes []*Change, opts ReviewOptions) (*ReviewResult, error) {
+	if opts.Yes || opts.DryRun {
+		written, err := Review(root, changes, opts)
+		return &ReviewResult{Written: written}, err
+	}
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	reader := bufio.NewReader(opts.In)
+
+	result := &ReviewResult{}
+	quit := false
+	for _, c := range changes {
+		if quit {
+			break
+		}
+		fileColor.Fprintf(opts.Out, "--- %s\n+++ b/%s\n", oldName(c), c.Path)
+		printStatus(opts.Out, c)
+		if c.Unchanged() {
+			noteColor.Fprintf(opts.Out, "%s: no changes\n", c.Path)
+			continue
+		}
+		if c.Conflicts > 0 {
+			errorColor.Fprintf(opts.Out, "Accepting a hunk with %q markers writes them to %s.\n", diff.MarkerOurs, c.Path)
+		}
+
+		hunks := diff.Hunks(diff.Lines(diff.SplitLines(c.Old), diff.SplitLines(c.New)), 3)
+		accepted := make([]bool, len(hunks))
+		for i := 0; i < len(hunks); {
+			printHunk(opts.Out, hunks[i])
+			answer, err := askHunk(reader, opts.Out, c, i, len(hunks), len(splitHunk(hunks[i])) > 1)
+			if err != nil {
+				return result, err
+			}
+			switch answer {
+			case "y":
+				accepted[i] = true
+				i++
+			case "n":
+				i++
+			case "a":
+				for ; i < len(hunks); i++ {
+					accepted[i] = true
+				}
+			case "d":
+				i = len(hunks)
+			case "q":
+				i, quit = len(hunks), true
+			case "s":
+				parts := splitHunk(hunks[i])
+				fmt.Fprintf(opts.Out, "Split into %d hunks.\n", len(parts))
+				hunks = append(hunks[:i], append(parts, hunks[i+1:]...)...)
+				accepted = append(accepted[:i], append(make([]bool, len(parts)), accepted[i+1:]...)...)
+			case "e":
+				edited, err := editHunk(hunks[i])
+				if errors.Is(err, editor.ErrAborted) {
+					fmt.Fprintln(opts.Out, "Edit aborted; the hunk is unchanged.")
+					continue
+				}
+				if err != nil {
+					errorColor.Fprintf(opts.Out, "✗ %v\n", err)
+					continue
+				}
+				hunks[i] = edited
+				accepted[i] = true
+				i++
+			case "f":
+				fmt.Fprint(opts.Out, "Note for the model: ")
+				line, err := reader.ReadString('\n')
+				if err != nil && line == "" {
+					return result, err
+				}
+				if note := strings.TrimSpace(line); note != "" {
+					result.Feedback = append(result.Feedback, Feedback{Path: c.Path, Hunk: formatHunk(hunks[i]), Note: note})
+				}
+			}
+		}
+
+		content, ok := applySelected(c.Old, hunks, accepted)
+		if !ok || (c.Exists && content == c.Old) {
+			noteColor.Fprintf(opts.Out, "Skipped %s\n", c.Path)
+			continue
+		}
+		selected := *c
+		selected.New = content
+		if err := Write(root, &selected); err != nil {
+			return result, err
+		}
+		result.Written = append(result.Written, &selected)
+		okColor.Fprintf(opts.Out, "✔ Wrote %s\n", c.Path)
+	}
+	return result, nil
+}
+
+// askHunk asks what to do with a hunk until it gets a valid answer.
+func askHunk(reader *bufio.Reader, out io.Writer, c *Change, n, total int, canSplit bool) (string, error) {
+	choices := "y,n,a,d,e,f,q,?"
+	if canSplit {
+		choices = "y,n,a,d,s,e,f,q,?"
+	}
+	for {
+		fmt.Fprintf(out, "(%d/%d) Apply this hunk to %s [%s]? ", n+1, total, c.Path, choices)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return "q", nil
+			}
+			return "", err
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case answer == "s" && !canSplit:
+			fmt.Fprintln(out, "Sorry, cannot split this hunk.")
+		case answer != "" && strings.Contains("ynadsefq", answer) && len(answer) == 1:
+			return answer, nil
+		default:
+			fmt.Fprintln(out, hunkHelp)
+		}
+	}
+}
+
+// printStatus prints the notes, diagnostics and merge state of a change.
+func printStatus(out io.Writer, c *Change) {
+	for _, note := range c.Notes {
+		noteColor.Fprintf(out, "note: %s: %s\n", c.Path, note)
+	}
+	for _, d := range c.Diagnostics {
+		errorColor.Fprintf(out, "✗ %s\n", d)
+	}
+	if c.Stale {
+		noteColor.Fprintf(out, "note: %s changed since it was grabbed; the edits were merged with the current content\n", c.Path)
+	}
+	if c.Conflicts > 0 {
+		errorColor.Fprintf(out, "✗ %s: %d merge conflicts marked with %q and %q\n", c.Path, c.Conflicts, diff.MarkerOurs, diff.MarkerTheirs)
+	}
+}
+
+func oldName(c *Change) string {
+	if !c.Exists {
+		return "/dev/null"
+	}
+	return "a/" + c.Path
+}
+
+// printHunk writes a colored hunk.
+func printHunk(w io.Writer, h diff.Hunk) {
+	hunkColor.Fprintln(w, h.Header())
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case diff.Insert:
+			addColor.Fprintln(w, "+"+l.Text)
+		case diff.Delete:
+			removeColor.Fprintln(w, "-"+l.Text)
+		default:
+			fmt.Fprintln(w, " "+l.Text)
+		}
+	}
+}
+
+// formatHunk returns a hunk in unified diff form.
+func formatHunk(h diff.Hunk) string {
+	var sb strings.Builder
+	sb.WriteString(h.Header() + "\n")
+	for _, l := range h.Lines {
+		sb.WriteString(diff.Prefix(l.Kind) + l.Text + "\n")
+	}
+	return sb.String()
+}
+
+// splitHunk splits a hunk at the unchanged lines between its changes, each part keeping the
+// unchanged lines around it as context. A hunk with a single run of changes is returned whole.
+func splitHunk(h diff.Hunk) []diff.Hunk {
+	// Find the runs of changed lines.
+	type run struct{ start, end int }
+	var runs []run
+	for i := 0; i < len(h.Lines); i++ {
+		if h.Lines[i].Kind == diff.Equal {
+			continue
+		}
+		start := i
+		for i < len(h.Lines) && h.Lines[i].Kind != diff.Equal {
+			i++
+		}
+		runs = append(runs, run{start, i})
+	}
+	if len(runs) < 2 {
+		return []diff.Hunk{h}
+	}
+
+	// oldNo and newNo are the line numbers of every line of the hunk.
+	oldNo, newNo := make([]int, len(h.Lines)), make([]int, len(h.Lines))
+	o, n := h.OldStart, h.NewStart
+	for i, l := range h.Lines {
+		oldNo[i], newNo[i] = o, n
+		if l.Kind != diff.Insert {
+			o++
+		}
+		if l.Kind != diff.Delete {
+			n++
+		}
+	}
+
+	var parts []diff.Hunk
+	for r := range runs {
+		from, to := 0, len(h.Lines)
+		if r > 0 {
+			from = runs[r-1].end
+		}
+		if r < len(runs)-1 {
+			to = runs[r+1].start
+		}
+		part := diff.Hunk{OldStart: oldNo[from], NewStart: newNo[from], Lines: h.Lines[from:to]}
+		for _, l := range part.Lines {
+			if l.Kind != diff.Insert {
+				part.OldLines++
+			}
+			if l.Kind != diff.Delete {
+				part.NewLines++
+			}
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// editHunk opens a hunk in the editor. Lines can be added with "+", removed lines kept by
+// turning their "-" into a space, and added lines dropped by deleting them; the context and
+// removed lines must otherwise stay as they were for the hunk to apply.
+func editHunk(h diff.Hunk) (diff.Hunk, error) {
+	text, err := editor.Edit(editor.Options{
+		Initial: formatHunk(h),
+		Help: `To remove '-' lines, make them ' ' lines (context).
+To remove '+' lines, delete them.
+Lines starting with # will be removed.
+If the patch applies cleanly, the edited hunk will be marked for applying.
+To abort, delete everything.`,
+		Pattern: "gorani-hunk-*.diff",
+		Raw:     true,
+	})
+	if err != nil {
+		return h, err
+	}
+
+	edited := diff.Hunk{OldStart: h.OldStart, NewStart: h.NewStart}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"), strings.HasPrefix(line, "#"):
+			continue
+		case line == "":
+			// Editors strip the space of empty context lines.
+			edited.Lines = append(edited.Lines, diff.Line{Kind: diff.Equal})
+		case line[0] == ' ':
+			edited.Lines = append(edited.Lines, diff.Line{Kind: diff.Equal, Text: line[1:]})
+		case line[0] == '-':
+			edited.Lines = append(edited.Lines, diff.Line{Kind: diff.Delete, Text: line[1:]})
+		case line[0] == '+':
+			edited.Lines = append(edited.Lines, diff.Line{Kind: diff.Insert, Text: line[1:]})
+		default:
+			return h, fmt.Errorf("the edited hunk has a line without ' ', '-' or '+': %q", line)
+		}
+	}
+	if !equalLines(edited.Old(), h.Old()) {
+		return h, errors.New("the edited hunk does not apply: its context and removed lines must stay as they were")
+	}
+	for _, l := range edited.Lines {
+		if l.Kind != diff.Insert {
+			edited.OldLines++
+		}
+		if l.Kind != diff.Delete {
+			edited.NewLines++
+		}
+	}
+	return edited, nil
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// applySelected applies the accepted hunks to old. Only the changed lines of each hunk are
+// replaced, so hunks split from one another never overlap through their shared context.
+// It reports false when no hunk was accepted.
+func applySelected(old string, hunks []diff.Hunk, accepted []bool) (string, bool) {
+	lines := diff.SplitLines(old)
+	var result []string
+	pos, any := 0, false
+	for i, h := range hunks {
+		if !accepted[i] {
+			continue
+		}
+		any = true
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			// Pure insertions name the line they follow.
+			start = h.OldStart
+		}
+		lead := 0
+		for lead < len(h.Lines) && h.Lines[lead].Kind == diff.Equal {
+			lead++
+		}
+		trail := len(h.Lines)
+		for trail > lead && h.Lines[trail-1].Kind == diff.Equal {
+			trail--
+		}
+		core := diff.Hunk{Lines: h.Lines[lead:trail]}
+		start += lead
+
+		result = append(result, lines[pos:start]...)
+		result = append(result, core.New()...)
+		pos = start + len(core.Old())
+	}
+	result = append(result, lines[pos:]...)
+	if !any {
+		return old, false
+	}
+
+	text := strings.Join(result, "\n")
+	if len(result) > 0 && (old == "" || strings.HasSuffix(old, "\n")) {
+		text += "\n"
+	}
+	return text, true
+}
+
+// FeedbackText renders review notes as Markdown for the model.
+func FeedbackText(feedback []Feedback) string {
+	var sb strings.Builder
+	for i, f := range feedback {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s:\n```diff\n%s```\nNote: %s\n", f.Path, f.Hunk, f.Note)
+	}
+	return sb.String()
+}
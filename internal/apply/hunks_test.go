@@ -0,0 +1,54 @@
+package apply
+
+import (
+	"agent/gorani/internal/diff"
+	"agent/gorani/internal/editor"
+	"errors"
+	"testing"
+)
+
+// reviewHunks returns the hunks ReviewHunks shows for a change from old to new.
+func reviewHunks(old, new string) []diff.Hunk {
+	return diff.Hunks(diff.Lines(diff.SplitLines(old), diff.SplitLines(new)), 1)
+}
+
+func TestEditHunkKeepsBlankAndTrailingContext(t *testing.T) {
+	inTempDir(t)
+	tests := []struct {
+		name, editor, old, new string
+	}{
+		{"blank last context line", "true", "a\nb\n\nd\n", "a\nB\n\nd\n"},
+		{"blank first context line", "true", "a\n\nb\nc\n", "a\n\nB\nc\n"},
+		{"trailing whitespace", "true", "a\nb\nc  \nd\n", "a\nB\nc  \nd\n"},
+		{"editor strips trailing spaces", `sed -i -e 's/[[:space:]]*$//'`, "a\nb\n\nd\n", "a\nB\n\nd\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.editor)
+			hunks := reviewHunks(tt.old, tt.new)
+			if len(hunks) != 1 {
+				t.Fatalf("got %d hunks, want 1", len(hunks))
+			}
+			edited, err := editHunk(hunks[0])
+			if err != nil {
+				t.Fatalf("editHunk: %v", err)
+			}
+			if got := formatHunk(edited); got != formatHunk(hunks[0]) {
+				t.Errorf("edited hunk =\n%s\nwant\n%s", got, formatHunk(hunks[0]))
+			}
+			got, ok := applySelected(tt.old, []diff.Hunk{edited}, []bool{true})
+			if !ok || got != tt.new {
+				t.Errorf("applySelected = %q, %v, want %q", got, ok, tt.new)
+			}
+		})
+	}
+}
+
+func TestEditHunkAbortsWhenEmptied(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("VISUAL", `sh -c ': > "$0"'`)
+	hunks := reviewHunks("a\nb\nc\n", "a\nB\nc\n")
+	if _, err := editHunk(hunks[0]); !errors.Is(err, editor.ErrAborted) {
+		t.Fatalf("editHunk of an emptied file = %v, want ErrAborted", err)
+	}
+}
@@ -0,0 +1,147 @@
+package apply
+
+import (
+	"regexp"
+	"strings"
+)
+
+// baseRE matches the "(base <hash>)" grab appends to a header, naming the snapshot sent.
+var baseRE = regexp.MustCompile(`\s+\(base ([0-9a-f]{6,64})\)$`)
+
+// headerPath returns the path of a ">>> path" header line.
+func headerPath(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, ">>> ")
+	if !ok {
+		return "", false
+	}
+	rest = baseRE.ReplaceAllString(strings.TrimSpace(rest), "")
+	path := strings.Trim(rest, "`*")
+	return path, path != ""
+}
+
+// headerBase returns the snapshot hash of a ">>> path (base <hash>)" header, or "".
+func headerBase(line string) string {
+	if m := baseRE.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// startsFile reports whether line starts another file: a header or a code fence naming a path.
+func startsFile(line string) bool {
+	if _, ok := headerPath(line); ok {
+		return true
+	}
+	_, info, ok := openingFence(line)
+	return ok && infoPath(info) != ""
+}
+
+// blockEnd returns the index of the first line after the body of a ">>> path" block starting at from.
+// A fenced body ends with its fence. Otherwise the body runs until the next file; a "---" or "==="
+// separator only ends it when the next file follows, so such lines can appear in the content.
+func blockEnd(lines []string, from int) int {
+	first := from
+	for first < len(lines) && strings.TrimSpace(lines[first]) == "" {
+		first++
+	}
+	if first < len(lines) {
+		if fence, _, ok := openingFence(lines[first]); ok {
+			end := closingFence(lines, first+1, fence)
+			if end < len(lines) {
+				end++
+			}
+			// Prose after the fence belongs to no file.
+			for end < len(lines) && !startsFile(lines[end]) {
+				end++
+			}
+			return end
+		}
+	}
+
+	for i := from; i < len(lines); i++ {
+		if startsFile(lines[i]) {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// blockContent turns the lines after a ">>> path" header into file content. A body starting with
+// a code fence ends at its closing fence, dropping any prose after it; otherwise trailing
+// separators and blank lines are dropped.
+func blockContent(lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 {
+		if fence, _, ok := openingFence(lines[0]); ok {
+			return joinContent(lines[1:closingFence(lines, 1, fence)])
+		}
+	}
+
+	// Drop the separator grab puts between files.
+	for len(lines) > 0 {
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if last == "" || last == "---" || last == "===" {
+			lines = lines[:len(lines)-1]
+			continue
+		}
+		break
+	}
+	return joinContent(lines)
+}
+
+// joinContent joins lines into file content ending with a single newline.
+func joinContent(lines []string) string {
+	content := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if content == "" {
+		return ""
+	}
+	return content + "\n"
+}
+
+// openingFence reports whether line opens a code block and returns its fence and info string.
+func openingFence(line string) (fence, info string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, marker := range []byte{'`', '~'} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == marker {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n], strings.TrimSpace(trimmed[n:]), true
+		}
+	}
+	return "", "", false
+}
+
+// closingFence returns the index of the line closing a block opened with fence, or len(lines).
+func closingFence(lines []string, from int, fence string) int {
+	for i := from; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// infoPath finds a file path in a code fence info string such as "go main.go" or "internal/x.go".
+func infoPath(info string) string {
+	for _, field := range strings.Fields(info) {
+		if looksLikePath(field) {
+			return field
+		}
+	}
+	return ""
+}
+
+// looksLikePath reports whether s is plausibly a file path rather than a language name.
+func looksLikePath(s string) bool {
+	if strings.ContainsAny(s, "=\"'{}()<>") {
+		return false
+	}
+	base := s[strings.LastIndex(s, "/")+1:]
+	dot := strings.LastIndex(base, ".")
+	return dot > 0 && dot < len(base)-1 || strings.Contains(s, "/") && base != ""
+}
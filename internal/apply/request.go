@@ -0,0 +1,162 @@
+package apply
+
+import (
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/snapshot"
+	"agent/gorani/internal/templates"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Request asks a model for targeted edits of files.
+type Request struct {
+	// Root is the directory the file paths are relative to.
+	Root string
+	// Task describes the change to make.
+	Task string
+	// Files are the paths sent along with the task.
+	Files []string
+	// Feedback holds review notes on earlier edits of the task, rendered by FeedbackText.
+	Feedback string
+	// Retries is how many times failed edits are sent back to be regenerated.
+	Retries int
+	// OnAttempt is called before every request with its 1-based number, if set.
+	OnAttempt func(attempt int)
+}
+
+// RequestEdits sends the task and files to the provider, asking for SEARCH/REPLACE blocks or
+// diffs, and applies the answer in memory. Edits that do not apply are reported back to the
+// model, which is asked to regenerate only those against the content with the other edits
+// applied. It returns the resulting changes and the edits that still failed after the retries.
+func RequestEdits(ctx context.Context, provider prompt.Provider, req Request) ([]*Change, []*Failure, error) {
+	data := templates.Data{Description: req.Task, Errors: req.Feedback}
+	bases := map[string]string{}
+	for _, path := range req.Files {
+		full, err := SafePath(req.Root, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := os.ReadFile(full)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		// Changes made to the files while the model answers are merged with its edits.
+		hash, err := snapshot.Record(path, content)
+		if err != nil {
+			return nil, nil, err
+		}
+		bases[snapshot.Key(path)] = hash
+		data.Files = append(data.Files, templates.File{Path: path, Content: string(content)})
+	}
+	text, err := templates.Render("edit", data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages := []prompt.Message{prompt.UserMessage(text)}
+	var changes []*Change
+	var failures []*Failure
+	for attempt := 1; ; attempt++ {
+		if req.OnAttempt != nil {
+			req.OnAttempt(attempt)
+		}
+		resp, err := provider.Complete(ctx, &prompt.Request{Messages: messages})
+		if err != nil {
+			return nil, nil, err
+		}
+		answer := resp.Message.Content
+		edits := ParseAnswer(answer)
+		for i := range edits {
+			if edits[i].Base == "" {
+				edits[i].Base = bases[snapshot.Key(edits[i].Path)]
+			}
+		}
+		if len(edits) == 0 {
+			if attempt == 1 {
+				return nil, nil, fmt.Errorf("the answer contains no file edits")
+			}
+			return nil, nil, fmt.Errorf("the retry answer contains no file edits; %d edits still fail", len(failures))
+		}
+
+		// Failed edits of files the retry answer leaves out still fail.
+		regenerated := map[string]bool{}
+		for _, e := range edits {
+			regenerated[e.Path] = true
+		}
+		var kept []*Failure
+		for _, f := range failures {
+			if !regenerated[f.Edit.Path] {
+				kept = append(kept, f)
+			}
+		}
+		var failed []*Failure
+		changes, failed, err = resolve(req.Root, changes, edits)
+		if err != nil {
+			return nil, nil, err
+		}
+		failures = append(kept, failed...)
+		if len(failures) == 0 || attempt > req.Retries {
+			return changes, failures, nil
+		}
+
+		retry, err := retryPrompt(req.Root, changes, failures)
+		if err != nil {
+			return nil, nil, err
+		}
+		messages = append(messages, prompt.AssistantMessage(answer), prompt.UserMessage(retry))
+	}
+}
+
+// retryPrompt reports the failed edits and the current content of their files.
+func retryPrompt(root string, changes []*Change, failures []*Failure) (string, error) {
+	var report strings.Builder
+	var data templates.Data
+	seen := map[string]bool{}
+	for _, f := range failures {
+		fmt.Fprintf(&report, "- %s\n\n%s\n", f, indent(f.Edit.Format()))
+		if seen[f.Edit.Path] {
+			continue
+		}
+		seen[f.Edit.Path] = true
+		content, err := currentContent(root, changes, f.Edit.Path)
+		if err != nil {
+			return "", err
+		}
+		data.Files = append(data.Files, templates.File{Path: f.Edit.Path, Content: content})
+	}
+	data.Errors = strings.TrimRight(report.String(), "\n")
+	return templates.Render("edit_retry", data)
+}
+
+// currentContent returns the content of path with the applied edits, or as it is on disk.
+func currentContent(root string, changes []*Change, path string) (string, error) {
+	for _, c := range changes {
+		if c.Path == path && c.Stale {
+			// The model works on the grabbed content, not the merge with later changes.
+			return c.theirs, nil
+		}
+		if c.Path == path {
+			return c.New, nil
+		}
+	}
+	full, err := SafePath(root, path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return string(data), nil
+}
+
+// indent indents text by four spaces so it reads as a code block inside a list item.
+func indent(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
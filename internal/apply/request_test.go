@@ -0,0 +1,117 @@
+package apply
+
+import (
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/prompt/fixturetest"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+const storeFile = `package store
+
+// Store keeps item counts.
+type Store struct {
+	items map[string]int
+}
+
+// Get returns the count of an item.
+func (s *Store) Get(name string) int {
+	return s.items[name]
+}
+`
+
+func TestRequestEditsReplay(t *testing.T) {
+	fixturetest.Replay(t, map[string]string{"store/store.go": storeFile})
+
+	// The first answer has an edit whose SEARCH section is not in the file; the second
+	// answer, to the retry prompt, corrects it.
+	attempts := 0
+	changes, failures, err := RequestEdits(context.Background(), prompt.DefaultProvider(), Request{
+		Root:      ".",
+		Task:      "Rename Get to Count and add a Len method returning the number of items.",
+		Files:     []string{"store/store.go"},
+		Retries:   1,
+		OnAttempt: func(int) { attempts++ },
+	})
+	if err != nil {
+		t.Fatalf("RequestEdits: %v", err)
+	}
+	if len(failures) > 0 {
+		t.Fatalf("failures: %v", failures)
+	}
+	if attempts != 2 {
+		t.Errorf("made %d requests, want 2", attempts)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	want := `package store
+
+// Store keeps item counts.
+type Store struct {
+	items map[string]int
+}
+
+// Count returns the count of an item.
+func (s *Store) Count(name string) int {
+	return s.items[name]
+}
+
+// Len returns the number of items.
+func (s *Store) Len() int {
+	return len(s.items)
+}
+`
+	if changes[0].New != want {
+		t.Errorf("New =\n%s\nwant\n%s", changes[0].New, want)
+	}
+
+	// Pasting writes the change and leaves gorani's own write out of later merges.
+	if err := Write(".", changes[0]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("store/store.go")
+	if err != nil || string(data) != want {
+		t.Errorf("store/store.go = %q, %v", data, err)
+	}
+}
+
+// scripted answers requests with the given answers, in order.
+type scripted []string
+
+func (s *scripted) Complete(ctx context.Context, req *prompt.Request) (*prompt.Response, error) {
+	answer := (*s)[0]
+	*s = (*s)[1:]
+	return &prompt.Response{Message: prompt.AssistantMessage(answer)}, nil
+}
+
+func TestRequestEditsKeepsFailuresLeftOutOfRetry(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeTestFile(t, "a.txt", "one\ntwo\n")
+	writeTestFile(t, "b.txt", "three\n")
+	first := "a.txt\n<<<<<<< SEARCH\none\n=======\nONE\n>>>>>>> REPLACE\n\n" +
+		"b.txt\n<<<<<<< SEARCH\nfour\n=======\nFOUR\n>>>>>>> REPLACE\n"
+	req := Request{Root: ".", Task: "Shout.", Files: []string{"a.txt", "b.txt"}, Retries: 1}
+
+	// The retry answer edits another part of a.txt but leaves out the failed edit of b.txt.
+	provider := &scripted{first, "a.txt\n<<<<<<< SEARCH\ntwo\n=======\nTWO\n>>>>>>> REPLACE\n"}
+	changes, failures, err := RequestEdits(context.Background(), provider, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].Edit.Path != "b.txt" {
+		t.Errorf("failures = %v, want the edit of b.txt", failures)
+	}
+	if len(changes) != 1 || changes[0].New != "ONE\nTWO\n" {
+		t.Errorf("changes = %v", changes)
+	}
+
+	// A retry answer with no edits at all is an error, not a success.
+	provider = &scripted{first, "Sorry, I cannot find that text."}
+	if _, _, err := RequestEdits(context.Background(), provider, req); err == nil || !strings.Contains(err.Error(), "no file edits") {
+		t.Errorf("err = %v, want the retry answer reported", err)
+	}
+}
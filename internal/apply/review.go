@@ -0,0 +1,142 @@
+package apply
+
+import (
+	"agent/gorani/internal/diff"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Colored formatters for diffs.
+var (
+	fileColor   = color.New(color.Bold)                // File headers in bold.
+	hunkColor   = color.New(color.FgCyan)              // Hunk headers in cyan.
+	addColor    = color.New(color.FgGreen)             // Added lines in green.
+	removeColor = color.New(color.FgRed)               // Removed lines in red.
+	noteColor   = color.New(color.FgHiBlack)           // Notes dimmed.
+	okColor     = color.New(color.FgGreen, color.Bold) // Written files in bold green.
+	errorColor  = color.New(color.FgRed, color.Bold)   // Diagnostics in bold red.
+)
+
+// PrintDiff writes the colored unified diff of a change.
+func PrintDiff(w io.Writer, c *Change) {
+	text := c.Diff()
+	if text == "" {
+		noteColor.Fprintf(w, "%s: no changes\n", c.Path)
+		return
+	}
+	for _, line := range diff.SplitLines(text) {
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			fileColor.Fprintln(w, line)
+		case strings.HasPrefix(line, "@@"):
+			hunkColor.Fprintln(w, line)
+		case strings.HasPrefix(line, "+"):
+			addColor.Fprintln(w, line)
+		case strings.HasPrefix(line, "-"):
+			removeColor.Fprintln(w, line)
+		default:
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// ReviewOptions controls Review.
+type ReviewOptions struct {
+	// Yes applies every change without asking, except those with conflicts or Go syntax errors,
+	// which are skipped.
+	Yes bool
+	// DryRun only shows the diffs.
+	DryRun bool
+	// In answers the questions. Defaults to stdin.
+	In io.Reader
+	// Out receives diffs and questions. Defaults to stdout.
+	Out io.Writer
+}
+
+// Review shows the diff of every change and writes the ones the user accepts.
+// It returns the changes that were written.
+func Review(root string, changes []*Change, opts ReviewOptions) ([]*Change, error) {
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	reader := bufio.NewReader(opts.In)
+
+	var written []*Change
+	all := opts.Yes
+	for _, c := range changes {
+		PrintDiff(opts.Out, c)
+		printStatus(opts.Out, c)
+		if c.Unchanged() || opts.DryRun {
+			continue
+		}
+		// Conflict markers and Go syntax errors are only written when the user accepts them,
+		// even after answering "a".
+		risky := c.Conflicts > 0 || c.HasSyntaxErrors()
+		if risky && opts.Yes {
+			reason := "with conflict markers"
+			if c.HasSyntaxErrors() {
+				reason = "with Go syntax errors"
+			}
+			errorColor.Fprintf(opts.Out, "Skipped %s: review it without --yes to write it %s\n", c.Path, reason)
+			continue
+		}
+
+		if !all || risky {
+			answer, err := ask(reader, opts.Out, c)
+			if err != nil {
+				return written, err
+			}
+			switch answer {
+			case "q":
+				return written, nil
+			case "n":
+				continue
+			case "a":
+				all = true
+			}
+		}
+
+		if err := Write(root, c); err != nil {
+			return written, err
+		}
+		written = append(written, c)
+		okColor.Fprintf(opts.Out, "✔ Wrote %s\n", c.Path)
+	}
+	return written, nil
+}
+
+// ask asks whether a change should be written until it gets a valid answer.
+func ask(reader *bufio.Reader, out io.Writer, c *Change) (string, error) {
+	verb := "Apply changes to"
+	if !c.Exists {
+		verb = "Create"
+	}
+	for {
+		fmt.Fprintf(out, "%s %s? [y,n,a,q,?] ", verb, c.Path)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return "q", nil
+			}
+			return "", err
+		}
+		switch answer := strings.ToLower(strings.TrimSpace(line)); answer {
+		case "y", "yes":
+			return "y", nil
+		case "n", "no", "":
+			return "n", nil
+		case "a", "q":
+			return answer, nil
+		default:
+			fmt.Fprintln(out, "y - write this file\nn - skip this file\na - write this and all remaining files\nq - quit, skipping the remaining files")
+		}
+	}
+}
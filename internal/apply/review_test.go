@@ -0,0 +1,58 @@
+package apply
+
+import (
+	"agent/gorani/internal/gocheck"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReviewHoldsBackRiskyChanges(t *testing.T) {
+	newChanges := func() []*Change {
+		return []*Change{
+			{Path: "good.txt", New: "good\n"},
+			{Path: "broken.go", New: "package broken\n\nfunc (\n", Diagnostics: []gocheck.Diagnostic{
+				{Path: "broken.go", Line: 3, Column: 6, Message: "expected ')', found newline", Syntax: true},
+			}},
+			{Path: "conflict.txt", New: "<<<<<<< current\na\n=======\nb\n>>>>>>> model\n", Conflicts: 1},
+		}
+	}
+	tests := []struct {
+		name  string
+		opts  ReviewOptions
+		want  []string
+		asked int
+	}{
+		{"yes skips them", ReviewOptions{Yes: true}, []string{"good.txt"}, 0},
+		// "a" writes the first file; the two others are still asked about, and declined.
+		{"all still asks", ReviewOptions{In: strings.NewReader("a\nn\nn\n")}, []string{"good.txt"}, 3},
+		{"all accepted one by one", ReviewOptions{In: strings.NewReader("a\ny\ny\n")}, []string{"good.txt", "broken.go", "conflict.txt"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			var out strings.Builder
+			tt.opts.Out = &out
+			written, err := Review(".", newChanges(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, c := range written {
+				paths = append(paths, c.Path)
+			}
+			if strings.Join(paths, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("wrote %v, want %v", paths, tt.want)
+			}
+			if asked := strings.Count(out.String(), "[y,n,a,q,?]"); asked != tt.asked {
+				t.Errorf("asked %d times, want %d:\n%s", asked, tt.asked, out.String())
+			}
+			for _, path := range []string{"broken.go", "conflict.txt"} {
+				_, err := os.Stat(path)
+				if wrote := err == nil; wrote != strings.Contains(strings.Join(tt.want, " "), path) {
+					t.Errorf("%s written = %v", path, wrote)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package apply
+
+import (
+	"agent/gorani/internal/snapshot"
+	"os"
+	"strings"
+	"testing"
+)
+
+// inTempDir runs the test in an empty directory, where gorani keeps its state.
+func inTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resolveOne(t *testing.T, answer string) *Change {
+	t.Helper()
+	changes, failures, err := ResolveAll(".", ParseAnswer(answer))
+	if err != nil || len(failures) > 0 || len(changes) != 1 {
+		t.Fatalf("ResolveAll = %v, %v, %v", changes, failures, err)
+	}
+	return changes[0]
+}
+
+func TestResolveRepeatedAnswersAreNotStale(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "m.go", "package m\n\nfunc F() int { return 1 }\n")
+	hash, err := snapshot.Record("m.go", []byte("package m\n\nfunc F() int { return 1 }\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := ">>> m.go (base " + snapshot.Short(hash) + ")\n"
+
+	first := resolveOne(t, header+"package m\n\nfunc F() int { return 2 }\n")
+	if first.Stale {
+		t.Fatal("the first answer is stale")
+	}
+	if err := Write(".", first); err != nil {
+		t.Fatal(err)
+	}
+
+	// A revised answer against the same grab replaces gorani's own write.
+	second := resolveOne(t, header+"package m\n\nfunc F() int { return 3 }\n")
+	if second.Stale || second.Conflicts > 0 {
+		t.Fatalf("Stale = %v, Conflicts = %d after gorani's own write", second.Stale, second.Conflicts)
+	}
+	if !strings.Contains(second.New, "return 3") {
+		t.Errorf("New = %q", second.New)
+	}
+}
+
+func TestResolveMergesUserChanges(t *testing.T) {
+	inTempDir(t)
+	grabbed := "package m\n\nfunc A() int { return 1 }\n\nfunc B() int { return 1 }\n"
+	writeTestFile(t, "m.go", grabbed)
+	hash, err := snapshot.Record("m.go", []byte(grabbed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The user changes A while the model changes B.
+	writeTestFile(t, "m.go", strings.Replace(grabbed, "A() int { return 1 }", "A() int { return 10 }", 1))
+
+	c := resolveOne(t, ">>> m.go (base "+snapshot.Short(hash)+")\n"+strings.Replace(grabbed, "B() int { return 1 }", "B() int { return 20 }", 1))
+	if !c.Stale || c.Conflicts != 0 {
+		t.Fatalf("Stale = %v, Conflicts = %d, want a clean merge", c.Stale, c.Conflicts)
+	}
+	if !strings.Contains(c.New, "return 10") || !strings.Contains(c.New, "return 20") {
+		t.Errorf("New = %q, want both changes", c.New)
+	}
+}
+
+func TestHeaderBase(t *testing.T) {
+	path, ok := headerPath(">>> internal/m.go (base 0123456789ab)")
+	if !ok || path != "internal/m.go" {
+		t.Errorf("headerPath = %q, %v", path, ok)
+	}
+	if base := headerBase(">>> internal/m.go (base 0123456789ab)"); base != "0123456789ab" {
+		t.Errorf("headerBase = %q", base)
+	}
+	if base := headerBase(">>> internal/m.go"); base != "" {
+		t.Errorf("headerBase = %q, want none", base)
+	}
+}
@@ -0,0 +1,46 @@
+package apply
+
+import (
+	"agent/gorani/internal/gocheck"
+	"strings"
+)
+
+// Validate checks the Go files among the changes before they are written: their imports are
+// fixed and they are formatted in place, and syntax and type errors of their packages are
+// attached to the changes as diagnostics.
+func Validate(root string, changes []*Change) error {
+	files := map[string]string{}
+	for _, c := range changes {
+		if strings.HasSuffix(c.Path, ".go") {
+			files[c.Path] = c.New
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	result, err := gocheck.Check(root, files)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		content, ok := result.Files[c.Path]
+		if !ok {
+			continue
+		}
+		c.New = content
+		c.Notes = append(c.Notes, result.Notes[c.Path]...)
+		c.Diagnostics = result.For(c.Path)
+	}
+	// Errors in files of the same packages that are not being changed are reported on the first change.
+	for _, d := range result.Diagnostics {
+		if _, ok := files[d.Path]; !ok {
+			for _, c := range changes {
+				if _, ok := files[c.Path]; ok {
+					c.Diagnostics = append(c.Diagnostics, d)
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"agent/gorani/internal/config"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvVar is the environment variable holding the OpenAI API key.
+const EnvVar = "OPENAI_API_KEY"
+
+// HelperEnvVar overrides the credential helper command of the user settings file.
+const HelperEnvVar = "GORANI_CREDENTIAL_HELPER"
+
+// ErrNoCredentials is returned when no source provides an API key.
+var ErrNoCredentials = errors.New("no OpenAI API key found: set " + EnvVar + " or run 'gorani auth login'")
+
+// Credential is a resolved API key and where it came from.
+type Credential struct {
+	APIKey string
+	Source string
+}
+
+var (
+	resolveOnce sync.Once
+	resolved    Credential
+	resolveErr  error
+)
+
+// Resolve returns the OpenAI API key, looking in order at the environment, the configured
+// credential helper, the user credentials file and a legacy .env file in the current directory.
+// The result is cached for the life of the process.
+func Resolve() (Credential, error) {
+	resolveOnce.Do(func() {
+		resolved, resolveErr = lookup()
+	})
+	return resolved, resolveErr
+}
+
+// lookup walks the credential sources without caching.
+func lookup() (Credential, error) {
+	if key := strings.TrimSpace(os.Getenv(EnvVar)); key != "" {
+		return Credential{APIKey: key, Source: "environment (" + EnvVar + ")"}, nil
+	}
+
+	if helper := helperCommand(); helper != "" {
+		key, err := runHelper(helper)
+		if err != nil {
+			return Credential{}, err
+		}
+		return Credential{APIKey: key, Source: "credential helper (" + helper + ")"}, nil
+	}
+
+	if path, err := CredentialsPath(); err == nil {
+		if key, err := readKeyFile(path); err == nil && key != "" {
+			return Credential{APIKey: key, Source: path}, nil
+		}
+	}
+
+	// Older versions wrote the key to .env in the project directory.
+	if key, err := readKeyFile(".env"); err == nil && key != "" {
+		return Credential{APIKey: key, Source: ".env (legacy, run 'gorani auth login' to move it)"}, nil
+	}
+
+	return Credential{}, ErrNoCredentials
+}
+
+// CredentialsPath returns the path of the user credentials file.
+func CredentialsPath() (string, error) {
+	dir, err := config.UserDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials"), nil
+}
+
+// Login stores the API key in the user credentials file, readable only by the user.
+func Login(apiKey string) (string, error) {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", fmt.Errorf("no API key provided")
+	}
+
+	path, err := CredentialsPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	content := fmt.Sprintf("%s=%s\n", EnvVar, apiKey)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	// WriteFile keeps the mode of an existing file, so tighten it explicitly.
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", fmt.Errorf("failed to restrict permissions of %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// Logout removes the user credentials file. It reports whether a file was removed.
+func Logout() (bool, error) {
+	path, err := CredentialsPath()
+	if err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to remove %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// Mask hides a key for display, showing only its first three and last four characters.
+// Keys of eight characters or fewer are hidden completely.
+func Mask(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:3] + strings.Repeat("*", 8) + key[len(key)-4:]
+}
+
+// CheckPermissions warns about credential files readable by other users.
+func CheckPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s has permissions %v, run 'chmod 600 %s'", path, info.Mode().Perm(), path)
+	}
+	return nil
+}
+
+// helperCommand returns the credential helper set in the environment or in the user settings
+// file, if any. A helper in the project settings.toml is ignored: it comes with the repository,
+// and running it would let any cloned project run commands.
+func helperCommand() string {
+	if helper := strings.TrimSpace(os.Getenv(HelperEnvVar)); helper != "" {
+		return helper
+	}
+	if project, err := config.Load(); err == nil && strings.TrimSpace(project.Auth.Helper) != "" {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring [auth] helper in %s; set it in the user settings file or %s\n", config.SettingsFile, HelperEnvVar)
+	}
+	settings, err := config.LoadUser()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(settings.Auth.Helper)
+}
+
+// runHelper runs the credential helper through the shell and returns the first line it prints.
+func runHelper(helper string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, helper)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("credential helper %q failed: %v\n%s", helper, err, stderr.String())
+	}
+
+	key, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("credential helper %q printed no key", helper)
+	}
+	return key, nil
+}
+
+// readKeyFile reads OPENAI_API_KEY from a dotenv-style file.
+func readKeyFile(path string) (string, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(values[EnvVar]), nil
+}
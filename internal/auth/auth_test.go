@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolate runs the test in an empty project with an empty user config directory and no key
+// in the environment.
+func isolate(t *testing.T) (userDir string) {
+	t.Helper()
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv(EnvVar, "")
+	t.Setenv(HelperEnvVar, "")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	userDir = filepath.Join(config, "gorani")
+	if err := os.MkdirAll(userDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return userDir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProjectHelperIsIgnored(t *testing.T) {
+	isolate(t)
+	writeFile(t, "settings.toml", "[auth]\nhelper = \"echo project-key\"\n")
+	if cred, err := lookup(); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("lookup = %+v, %v; the project helper ran", cred, err)
+	}
+}
+
+func TestUserHelper(t *testing.T) {
+	userDir := isolate(t)
+	writeFile(t, "settings.toml", "[auth]\nhelper = \"echo project-key\"\n")
+	writeFile(t, filepath.Join(userDir, "settings.toml"), "[auth]\nhelper = \"echo user-key\"\n")
+	cred, err := lookup()
+	if err != nil || cred.APIKey != "user-key" {
+		t.Fatalf("lookup = %+v, %v, want the user helper's key", cred, err)
+	}
+
+	t.Setenv(HelperEnvVar, "echo env-key")
+	if cred, err := lookup(); err != nil || cred.APIKey != "env-key" {
+		t.Fatalf("lookup = %+v, %v, want the key of %s", cred, err, HelperEnvVar)
+	}
+}
+
+func TestLoginAndLookup(t *testing.T) {
+	isolate(t)
+	path, err := Login("  sk-stored  \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPermissions(path); err != nil {
+		t.Error(err)
+	}
+	if cred, err := lookup(); err != nil || cred.APIKey != "sk-stored" || cred.Source != path {
+		t.Fatalf("lookup = %+v, %v", cred, err)
+	}
+	if removed, err := Logout(); !removed || err != nil {
+		t.Fatalf("Logout = %v, %v", removed, err)
+	}
+}
+
+func TestMask(t *testing.T) {
+	for key, want := range map[string]string{
+		"":                     "",
+		"short":                "*****",
+		"12345678":             "********",
+		"sk-abcdefghijkl1234":  "sk-********1234",
+		"sk-proj-0123456789ab": "sk-********89ab",
+	} {
+		if got := Mask(key); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Store is a content-addressed cache of files under Dir.
+// Entries older than TTL are ignored and the oldest entries are evicted once the
+// total size exceeds MaxBytes.
+type Store struct {
+	Dir string
+	// TTL is how long an entry stays valid. Zero keeps entries forever.
+	TTL time.Duration
+	// MaxBytes bounds the total size of the cache. Zero means unbounded.
+	MaxBytes int64
+}
+
+// Stats describes the contents of a store.
+type Stats struct {
+	Entries int
+	Bytes   int64
+	// Expired counts entries older than the TTL that have not been removed yet.
+	Expired int
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Key hashes the given parts into a cache key.
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the entry stored under key, if present and not expired.
+func (s *Store) Get(key string) ([]byte, bool) {
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if err != nil || s.expired(info, time.Now()) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores data under key and evicts old entries when the store is over its size limit.
+func (s *Store) Put(key string, data []byte) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	// Write to a temporary file first so concurrent readers never see a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	return s.Prune()
+}
+
+// Prune removes expired entries, then the least recently written ones until the store fits MaxBytes.
+func (s *Store) Prune() error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var total int64
+	var live []entry
+	for _, e := range entries {
+		if s.expired(e.info, now) {
+			os.Remove(e.path)
+			continue
+		}
+		total += e.info.Size()
+		live = append(live, e)
+	}
+	if s.MaxBytes <= 0 || total <= s.MaxBytes {
+		return nil
+	}
+
+	sort.Slice(live, func(i, j int) bool { return live[i].info.ModTime().Before(live[j].info.ModTime()) })
+	for _, e := range live {
+		if total <= s.MaxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err == nil {
+			total -= e.info.Size()
+		}
+	}
+	return nil
+}
+
+// Stats counts the entries in the store.
+func (s *Store) Stats() (Stats, error) {
+	entries, err := s.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+	now := time.Now()
+	var stats Stats
+	for _, e := range entries {
+		stats.Entries++
+		stats.Bytes += e.info.Size()
+		if s.expired(e.info, now) {
+			stats.Expired++
+		}
+		modified := e.info.ModTime()
+		if stats.Oldest.IsZero() || modified.Before(stats.Oldest) {
+			stats.Oldest = modified
+		}
+		if modified.After(stats.Newest) {
+			stats.Newest = modified
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every entry and returns how many were removed.
+func (s *Store) Clear() (int, error) {
+	entries, err := s.entries()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(s.Dir); err != nil {
+		return 0, fmt.Errorf("failed to remove %s: %v", s.Dir, err)
+	}
+	return len(entries), nil
+}
+
+// entry is a cache file found on disk.
+type entry struct {
+	path string
+	info fs.FileInfo
+}
+
+// entries lists the cache files; a missing directory is an empty cache.
+func (s *Store) entries() ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path: path, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache %s: %v", s.Dir, err)
+	}
+	return entries, nil
+}
+
+// path shards entries into subdirectories named after the first two characters of the key.
+func (s *Store) path(key string) string {
+	if len(key) < 3 {
+		return filepath.Join(s.Dir, key)
+	}
+	return filepath.Join(s.Dir, key[:2], key[2:])
+}
+
+func (s *Store) expired(info fs.FileInfo, now time.Time) bool {
+	return s.TTL > 0 && now.Sub(info.ModTime()) > s.TTL
+}
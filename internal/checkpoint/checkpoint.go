@@ -0,0 +1,296 @@
+package checkpoint
+
+import (
+	"agent/gorani/internal/config"
+	"agent/gorani/internal/journal"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RefPrefix is where checkpoints are kept. Refs outside refs/heads and refs/tags are hidden from
+// git branch and git tag, and are not pushed by default.
+const RefPrefix = "refs/gorani/checkpoints/"
+
+// maxCheckpoints is the number of checkpoints kept; older ones are deleted.
+const maxCheckpoints = 100
+
+// Checkpoint is a commit of the whole working tree, tracked and untracked files, made before
+// gorani wrote files.
+type Checkpoint struct {
+	// ID is the journal run that wrote files after the checkpoint was taken.
+	ID     string
+	Commit string
+	Time   time.Time
+	// Message names the command the checkpoint was taken for.
+	Message string
+}
+
+// Ref returns the full name of the checkpoint's ref.
+func (c *Checkpoint) Ref() string {
+	return RefPrefix + c.ID
+}
+
+// git runs a git command, with extra environment variables, and returns its trimmed output.
+func git(env []string, args ...string) (string, error) {
+	out, err := gitOutput(env, args...)
+	return strings.TrimRight(string(out), "\n"), err
+}
+
+// gitOutput runs a git command and returns its output as is.
+func gitOutput(env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return out, nil
+}
+
+// InRepo reports whether gorani runs inside a git work tree.
+func InRepo() bool {
+	out, err := git(nil, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// snapshotTree writes the working tree, including untracked files that are not ignored, as a
+// git tree and returns its hash. It stages into a copy of the index, leaving the user's alone.
+func snapshotTree() (string, error) {
+	indexPath, err := git(nil, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp("", "gorani-index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary index: %v", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	// Starting from the real index lets git skip hashing the files that did not change.
+	if data, err := os.ReadFile(indexPath); err == nil {
+		if err := os.WriteFile(tmp.Name(), data, 0600); err != nil {
+			return "", fmt.Errorf("failed to copy the index: %v", err)
+		}
+	} else {
+		os.Remove(tmp.Name())
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + tmp.Name()}
+	args := []string{"add", "--all", "--", ":/"}
+	if !filepath.IsAbs(config.StateDir) {
+		// gorani's own state is not part of the project.
+		args = append(args, ":(exclude)"+config.StateDir)
+	}
+	if _, err := git(env, args...); err != nil {
+		return "", err
+	}
+	return git(env, "write-tree")
+}
+
+// Create commits the working tree under RefPrefix+id, with HEAD as parent when there is one.
+// Outside a git work tree it does nothing and returns nil.
+func Create(id, message string) (*Checkpoint, error) {
+	if !InRepo() {
+		return nil, nil
+	}
+	tree, err := snapshotTree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint the working tree: %v", err)
+	}
+	args := []string{"commit-tree", tree, "-m", message}
+	if head, err := git(nil, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil && head != "" {
+		args = append(args, "-p", head)
+	}
+	// Checkpoints are gorani's commits, and must not fail for want of a configured identity.
+	env := []string{
+		"GIT_AUTHOR_NAME=gorani", "GIT_AUTHOR_EMAIL=gorani@localhost",
+		"GIT_COMMITTER_NAME=gorani", "GIT_COMMITTER_EMAIL=gorani@localhost",
+	}
+	commit, err := git(env, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint the working tree: %v", err)
+	}
+	c := &Checkpoint{ID: id, Commit: commit, Time: time.Now(), Message: message}
+	if _, err := git(nil, "update-ref", "-m", "gorani checkpoint", c.Ref(), commit); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint the working tree: %v", err)
+	}
+	prune()
+	return c, nil
+}
+
+// List returns the checkpoints, newest first.
+func List() ([]*Checkpoint, error) {
+	if !InRepo() {
+		return nil, fmt.Errorf("not inside a git repository")
+	}
+	out, err := git(nil, "for-each-ref", "--format=%(refname)%09%(objectname)%09%(committerdate:unix)%09%(subject)", RefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []*Checkpoint
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[2], 10, 64)
+		checkpoints = append(checkpoints, &Checkpoint{
+			ID:      strings.TrimPrefix(fields[0], RefPrefix),
+			Commit:  fields[1],
+			Time:    time.Unix(unix, 0),
+			Message: fields[3],
+		})
+	}
+	// IDs start with the time, so they sort chronologically.
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].ID > checkpoints[j].ID })
+	return checkpoints, nil
+}
+
+// Find returns the checkpoint whose ID starts with prefix, or the latest one when prefix is empty.
+func Find(prefix string) (*Checkpoint, error) {
+	checkpoints, err := List()
+	if err != nil {
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, fmt.Errorf("no checkpoints yet: they are taken before gorani writes files in a git repository")
+	}
+	if prefix == "" {
+		return checkpoints[0], nil
+	}
+	var found *Checkpoint
+	for _, c := range checkpoints {
+		if strings.HasPrefix(c.ID, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("checkpoint %q is ambiguous", prefix)
+			}
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no checkpoint %q", prefix)
+	}
+	return found, nil
+}
+
+// Diff returns the diff from a checkpoint to the working tree, or its diffstat.
+func Diff(c *Checkpoint, stat, color bool) (string, error) {
+	tree, err := snapshotTree()
+	if err != nil {
+		return "", err
+	}
+	args := []string{"diff", "--no-ext-diff"}
+	if color {
+		args = append(args, "--color=always")
+	}
+	if stat {
+		args = append(args, "--stat")
+	}
+	return git(nil, append(args, c.Commit, tree)...)
+}
+
+// Restore makes the working tree match a checkpoint: changed and deleted files get their
+// checkpointed content back and files created since are removed. The writes are journaled, so
+// gorani undo reverts a restore. It returns the paths it changed, relative to the current directory.
+func Restore(c *Checkpoint) ([]string, error) {
+	current, err := snapshotTree()
+	if err != nil {
+		return nil, err
+	}
+	cdup, err := git(nil, "rev-parse", "--show-cdup")
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(nil, "diff-tree", "-r", "-z", "--no-renames", "--name-status", current, c.Commit)
+	if err != nil {
+		return nil, err
+	}
+	modes, err := treeModes(c.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var restored []string
+	fields := strings.Split(strings.TrimRight(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, name := fields[i], fields[i+1]
+		path := filepath.Join(cdup, filepath.FromSlash(name))
+		if status == "D" {
+			// Created after the checkpoint.
+			if err := journal.Remove(path); err != nil {
+				return restored, fmt.Errorf("failed to remove %s: %v", path, err)
+			}
+			restored = append(restored, path)
+			continue
+		}
+
+		var perm os.FileMode
+		switch modes[name] {
+		case "100644":
+			perm = 0644
+		case "100755":
+			perm = 0755
+		default:
+			// Symlinks and submodules are left as they are.
+			continue
+		}
+		content, err := gitOutput(nil, "cat-file", "blob", c.Commit+":"+name)
+		if err != nil {
+			return restored, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return restored, fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := journal.WriteFile(path, content, perm); err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %v", path, err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %v", path, err)
+		}
+		restored = append(restored, path)
+	}
+	return restored, nil
+}
+
+// treeModes maps every file of a commit to its git mode.
+func treeModes(commit string) (map[string]string, error) {
+	out, err := git(nil, "ls-tree", "-r", "-z", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+	modes := map[string]string{}
+	for _, entry := range strings.Split(out, "\x00") {
+		// <mode> SP <type> SP <object> TAB <path>
+		meta, name, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+		mode, _, _ := strings.Cut(meta, " ")
+		modes[name] = mode
+	}
+	return modes, nil
+}
+
+// prune deletes the oldest checkpoints beyond maxCheckpoints.
+func prune() {
+	checkpoints, err := List()
+	if err != nil || len(checkpoints) <= maxCheckpoints {
+		return
+	}
+	for _, c := range checkpoints[maxCheckpoints:] {
+		git(nil, "update-ref", "-d", c.Ref())
+	}
+}
@@ -2,6 +2,7 @@ package commandbuilder
 
 import (
 	"agent/gorani/internal/grab"
+	"agent/gorani/internal/journal"
 	"bufio"
 	"fmt"
 	"os"
@@ -106,7 +107,7 @@ func RegisterActions() {
 	code += "}\n"
 
 	// Write the generated code to the file.
-	if err := os.WriteFile(outputFile, []byte(code), 0644); err != nil {
+	if err := journal.WriteFile(outputFile, []byte(code), 0644); err != nil {
 		fmt.Printf("Error writing file %s: %v\n", outputFile, err)
 		return
 	}
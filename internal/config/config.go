@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SettingsFile is the project settings file, read from the current directory.
+const SettingsFile = "settings.toml"
+
+// StateDir is where gorani keeps per-project state such as sessions, relative to the current directory.
+const StateDir = ".gorani"
+
+// DefaultModel is used when settings.toml does not name a model.
+const DefaultModel = "gpt-4o-2024-08-06"
+
+// Settings mirrors the contents of settings.toml.
+type Settings struct {
+	OpenAI OpenAISettings `toml:"openai"`
+	Auth   AuthSettings   `toml:"auth"`
+	Cache  CacheSettings  `toml:"cache"`
+	Editor EditorSettings `toml:"editor"`
+	Runner RunnerSettings `toml:"runner"`
+	// Prices maps model names to their token prices, used for cost reports.
+	Prices map[string]Price `toml:"prices"`
+}
+
+// OpenAISettings holds the [openai] section of settings.toml.
+type OpenAISettings struct {
+	Model string `toml:"model"`
+	// Timeout bounds each request attempt, e.g. "2m".
+	Timeout time.Duration `toml:"timeout"`
+	// MaxRetries is the number of retries after a rate limit, server error or timeout.
+	MaxRetries int `toml:"max_retries"`
+}
+
+// AuthSettings holds the [auth] section of the user settings file. It is ignored in the project
+// settings.toml, which comes with the repository and must not run commands on its own.
+type AuthSettings struct {
+	// Helper is a shell command printing the API key, e.g. "pass show openai"
+	// or "security find-generic-password -w -s openai".
+	Helper string `toml:"helper"`
+}
+
+// CacheSettings holds the [cache] section of settings.toml.
+type CacheSettings struct {
+	// Disabled turns the response cache off.
+	Disabled bool `toml:"disabled"`
+	// TTL is how long cached answers stay valid, e.g. "168h".
+	TTL time.Duration `toml:"ttl"`
+	// MaxMB bounds the size of the cache in megabytes.
+	MaxMB int64 `toml:"max_mb"`
+}
+
+// EditorSettings holds the [editor] section of the user settings file. Like AuthSettings, it is
+// ignored in the project settings.toml, since the editor is run through the shell.
+type EditorSettings struct {
+	// Command overrides $VISUAL and $EDITOR, e.g. "code --wait".
+	Command string `toml:"command"`
+}
+
+// RunnerSettings holds the [runner] section of settings.toml.
+type RunnerSettings struct {
+	// Allow lists the commands that may be run for the model, each a program optionally followed
+	// by its leading arguments, e.g. "go test" or "make lint". It replaces DefaultAllow.
+	Allow []string `toml:"allow"`
+	// Timeout bounds every command, e.g. "10m".
+	Timeout time.Duration `toml:"timeout"`
+	// MaxOutputKB caps the captured stdout and the captured stderr of a command.
+	MaxOutputKB int `toml:"max_output_kb"`
+	// Env names environment variables passed to commands besides the safe defaults.
+	Env []string `toml:"env"`
+}
+
+// DefaultAllow are the commands the runner allows when settings.toml lists none.
+var DefaultAllow = []string{"go build", "go vet", "go test", "gofmt"}
+
+// Price is the cost of a model in US dollars per million tokens.
+type Price struct {
+	Input  float64 `toml:"input"`
+	Output float64 `toml:"output"`
+}
+
+// PriceFor returns the price of model. A model without an exact entry uses the longest
+// entry that prefixes its name, so "gpt-4o" also prices "gpt-4o-2024-08-06".
+func (s *Settings) PriceFor(model string) (Price, bool) {
+	if price, ok := s.Prices[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range s.Prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return s.Prices[best], true
+}
+
+// Load reads settings.toml from the current directory and fills in defaults.
+// A missing file is not an error.
+func Load() (*Settings, error) {
+	settings := &Settings{}
+	if _, err := toml.DecodeFile(SettingsFile, settings); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %v", SettingsFile, err)
+	}
+
+	if settings.OpenAI.Model == "" {
+		settings.OpenAI.Model = DefaultModel
+	}
+	if settings.Cache.TTL == 0 {
+		settings.Cache.TTL = 7 * 24 * time.Hour
+	}
+	if settings.Cache.MaxMB == 0 {
+		settings.Cache.MaxMB = 100
+	}
+	if len(settings.Runner.Allow) == 0 {
+		settings.Runner.Allow = DefaultAllow
+	}
+	if settings.Runner.Timeout == 0 {
+		settings.Runner.Timeout = 5 * time.Minute
+	}
+	if settings.Runner.MaxOutputKB == 0 {
+		settings.Runner.MaxOutputKB = 64
+	}
+	return settings, nil
+}
+
+// UserSettingsPath returns the path of the user settings file, ~/.config/gorani/settings.toml.
+func UserSettingsPath() (string, error) {
+	dir, err := UserDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SettingsFile), nil
+}
+
+// LoadUser reads the user settings file without filling in defaults. Only its [auth] and
+// [editor] sections are used. A missing file is not an error.
+func LoadUser() (*Settings, error) {
+	path, err := UserSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+	settings := &Settings{}
+	if _, err := toml.DecodeFile(path, settings); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return settings, nil
+}
+
+// UserDir returns the per-user configuration directory, ~/.config/gorani
+// (or $XDG_CONFIG_HOME/gorani when set).
+func UserDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gorani"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "gorani"), nil
+}
+
+// StatePath joins elem onto the state directory.
+func StatePath(elem ...string) string {
+	return filepath.Join(append([]string{StateDir}, elem...)...)
+}
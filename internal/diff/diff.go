@@ -0,0 +1,278 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind tells whether a line is kept, removed or added.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Line is one line of a line-by-line comparison.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// SplitLines splits text into lines without their line endings.
+// A missing final newline does not produce an empty last line.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines compares a and b line by line with Myers' algorithm and returns the shortest edit script.
+// It uses the linear space variant, which splits the comparison at the middle snake of the edit
+// path, so memory stays proportional to the input even when every line changed.
+func Lines(a, b []string) []Line {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	return compare(make([]Line, 0, len(a)+len(b)), a, b)
+}
+
+// compare appends the edit script from a to b to script.
+func compare(script []Line, a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		script = append(script, Line{Equal, a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			script = append(script, Line{Insert, line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			script = append(script, Line{Delete, line})
+		}
+	default:
+		// With the common ends removed and both sides left, at least two edits are needed,
+		// so both halves around the middle snake are smaller problems.
+		x, y, u, v := middleSnake(a, b)
+		script = compare(script, a[:x], b[:y])
+		for _, line := range a[x:u] {
+			script = append(script, Line{Equal, line})
+		}
+		script = compare(script, a[u:], b[v:])
+	}
+	for _, line := range common {
+		script = append(script, Line{Equal, line})
+	}
+	return script
+}
+
+// middleSnake runs the search for the shortest edit path forward from the start and backward
+// from the end at once, and returns the snake where they meet, from (x, y) to (u, v).
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	// forward[k+offset] is the furthest x reached on diagonal k from the start, backward[k+offset]
+	// the smallest x reached on diagonal k from the end.
+	offset := 2*(n+m) + 2
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	forward[1+offset] = 0
+	backward[delta+1+offset] = n + 1
+
+	for d := 0; d <= (n+m+1)/2; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[k-1+offset] < forward[k+1+offset]) {
+				x = forward[k+1+offset]
+			} else {
+				x = forward[k-1+offset] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[k+offset] = x
+			if odd && k >= delta-(d-1) && k <= delta+(d-1) && x >= backward[k+offset] {
+				return startX, startY, x, y
+			}
+		}
+		for c := -d; c <= d; c += 2 {
+			k := c + delta
+			var x int
+			if c == -d || (c != d && backward[k+1+offset]-1 < backward[k-1+offset]) {
+				x = backward[k+1+offset] - 1
+			} else {
+				x = backward[k-1+offset]
+			}
+			y := x - k
+			endX, endY := x, y
+			for x > 0 && y > 0 && a[x-1] == b[y-1] {
+				x--
+				y--
+			}
+			backward[k+offset] = x
+			if !odd && k >= -d && k <= d && forward[k+offset] >= x {
+				return x, y, endX, endY
+			}
+		}
+	}
+	// The searches always meet by then.
+	panic("diff: no middle snake")
+}
+
+// Hunk is a group of changes with surrounding context, as in a unified diff.
+type Hunk struct {
+	// OldStart and NewStart are 1-based line numbers; OldLines and NewLines count the lines covered.
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Header returns the "@@ -a,b +c,d @@" line of the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", span(h.OldStart, h.OldLines), span(h.NewStart, h.NewLines))
+}
+
+func span(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Hunks groups an edit script into hunks with the given number of context lines.
+func Hunks(script []Line, context int) []Hunk {
+	var hunks []Hunk
+	oldLine, newLine := 1, 1
+	i := 0
+	for i < len(script) {
+		// Skip to the next change.
+		for i < len(script) && script[i].Kind == Equal {
+			i++
+			oldLine++
+			newLine++
+		}
+		if i == len(script) {
+			break
+		}
+
+		// Back up to include leading context.
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		for start > 0 && script[start-1].Kind != Equal {
+			start--
+		}
+		hunk := Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+
+		// Extend until a run of more than 2*context unchanged lines.
+		end := i
+		for end < len(script) {
+			if script[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(script) && script[run].Kind == Equal {
+				run++
+			}
+			if run == len(script) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunk.Lines = script[start:end]
+		for _, l := range hunk.Lines {
+			if l.Kind != Insert {
+				hunk.OldLines++
+			}
+			if l.Kind != Delete {
+				hunk.NewLines++
+			}
+		}
+		// Empty sides start one line earlier, as in diff -u.
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+
+		for _, l := range script[i:end] {
+			if l.Kind != Insert {
+				oldLine++
+			}
+			if l.Kind != Delete {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return hunks
+}
+
+// Unified returns a unified diff of old and new with three lines of context,
+// or "" when they are equal.
+func Unified(oldName, newName, old, new string) string {
+	hunks := Hunks(Lines(SplitLines(old), SplitLines(new)), 3)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		sb.WriteString(h.Header() + "\n")
+		for _, l := range h.Lines {
+			sb.WriteString(Prefix(l.Kind) + l.Text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Prefix returns the unified diff marker of a line kind.
+func Prefix(kind Kind) string {
+	switch kind {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	}
+	return " "
+}
+
+// Stat counts the added and removed lines of an edit script.
+func Stat(script []Line) (added, removed int) {
+	for _, l := range script {
+		switch l.Kind {
+		case Insert:
+			added++
+		case Delete:
+			removed++
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// checkScript fails unless script turns a into b with the fewest edits.
+func checkScript(t *testing.T, a, b []string, script []Line) {
+	t.Helper()
+	var old, new []string
+	equal := 0
+	for _, l := range script {
+		switch l.Kind {
+		case Equal:
+			old, new = append(old, l.Text), append(new, l.Text)
+			equal++
+		case Delete:
+			old = append(old, l.Text)
+		case Insert:
+			new = append(new, l.Text)
+		}
+	}
+	if strings.Join(old, "\n") != strings.Join(a, "\n") || strings.Join(new, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("script does not turn %q into %q: %v", a, b, script)
+	}
+	if want := lcs(a, b); equal != want {
+		t.Fatalf("script for %q -> %q keeps %d lines, want %d", a, b, equal, want)
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"", ""},
+		{"a", ""},
+		{"", "a"},
+		{"a", "a"},
+		{"a", "b"},
+		{"abc", "abc"},
+		{"abc", "ab"},
+		{"abc", "bc"},
+		{"abc", "axc"},
+		{"ab", "ba"},
+		{"abcabba", "cbabac"},
+		{"aaaa", "aa"},
+		{"abcdef", "fedcba"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Split(tt.a, ""), strings.Split(tt.b, "")
+		checkScript(t, a, b, Lines(a, b))
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 5000; i++ {
+		a, b := random(), random()
+		checkScript(t, a, b, Lines(a, b))
+	}
+}
+
+// TestLinesAllChanged compares files that share no line, like a file whose line endings
+// changed from LF to CRLF. It used to take seconds and a gigabyte of memory.
+func TestLinesAllChanged(t *testing.T) {
+	a, b := make([]string, 4000), make([]string, 4000)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d", i)
+		b[i] = a[i] + "\r"
+	}
+	start := time.Now()
+	script := Lines(a, b)
+	if len(script) != len(a)+len(b) {
+		t.Fatalf("got %d lines in the script, want %d", len(script), len(a)+len(b))
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("comparing took %s", elapsed)
+	}
+}
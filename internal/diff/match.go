@@ -0,0 +1,261 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level tells how loosely a block of lines was matched.
+type Level int
+
+const (
+	// Exact matched every line as is.
+	Exact Level = iota
+	// Whitespace matched lines ignoring indentation and spacing.
+	Whitespace
+	// Fuzzy matched after dropping some context lines at the edges of a hunk, like patch's fuzz factor.
+	Fuzzy
+)
+
+func (l Level) String() string {
+	switch l {
+	case Whitespace:
+		return "ignoring whitespace"
+	case Fuzzy:
+		return "with fuzz"
+	}
+	return "exactly"
+}
+
+// maxFuzz is the most context lines dropped from either end of a hunk.
+const maxFuzz = 2
+
+// Normalize collapses all whitespace in a line, for whitespace-insensitive comparison.
+func Normalize(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// Locate finds want in lines at or after from: exactly if possible, otherwise ignoring whitespace.
+// Among several matches the one closest to hint wins; unique reports whether there was only one.
+func Locate(lines, want []string, hint, from int) (at int, level Level, unique bool, ok bool) {
+	for _, level := range []Level{Exact, Whitespace} {
+		equal := equalLine(level)
+		best, bestDistance, count := -1, 0, 0
+		for i := from; i+len(want) <= len(lines); i++ {
+			if !matchAt(lines, want, i, equal) {
+				continue
+			}
+			count++
+			distance := i - hint
+			if distance < 0 {
+				distance = -distance
+			}
+			if best < 0 || distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+		if best >= 0 {
+			return best, level, count == 1, true
+		}
+	}
+	return 0, Exact, false, false
+}
+
+func equalLine(level Level) func(a, b string) bool {
+	if level == Exact {
+		return func(a, b string) bool { return a == b }
+	}
+	return func(a, b string) bool { return Normalize(a) == Normalize(b) }
+}
+
+func matchAt(lines, want []string, at int, equal func(a, b string) bool) bool {
+	for i, w := range want {
+		if !equal(lines[at+i], w) {
+			return false
+		}
+	}
+	return true
+}
+
+// Closest describes where want almost matches lines, for failure reports:
+// the 1-based line of the window sharing the most lines with want, and how many it shares.
+func Closest(lines, want []string) (line, matched int) {
+	if len(want) == 0 {
+		return 0, 0
+	}
+	normalized := make([]string, len(want))
+	for i, w := range want {
+		normalized[i] = Normalize(w)
+	}
+	best, bestCount := -1, 0
+	for at := 0; at < len(lines); at++ {
+		count := 0
+		for i := range want {
+			if at+i < len(lines) && Normalize(lines[at+i]) == normalized[i] && normalized[i] != "" {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = at, count
+		}
+	}
+	return best + 1, bestCount
+}
+
+// HunkResult reports how a hunk was applied.
+type HunkResult struct {
+	Hunk Hunk
+	// Line is the 1-based line where the hunk was applied.
+	Line  int
+	Level Level
+	// Err is set when the hunk could not be applied.
+	Err error
+}
+
+// ApplyHunks applies every hunk that matches and reports on each. Hunks are located exactly,
+// then ignoring whitespace, then with up to maxFuzz context lines dropped at either end,
+// searching outward from the line the hunk names so that wrong line numbers do not matter.
+func ApplyHunks(text string, hunks []Hunk) (string, []HunkResult) {
+	lines := SplitLines(text)
+	results := make([]HunkResult, 0, len(hunks))
+	// delta tracks how far earlier hunks shifted the line numbers.
+	delta := 0
+	for _, h := range hunks {
+		result := HunkResult{Hunk: h}
+		at, trimmed, level, ok := locateHunk(lines, h, h.OldStart-1+delta)
+		if !ok {
+			line, matched := Closest(lines, h.Old())
+			result.Err = fmt.Errorf("%s does not match the file", h.Header())
+			if matched > 0 {
+				result.Err = fmt.Errorf("%s does not match the file; closest match at line %d (%d of %d lines)",
+					h.Header(), line, matched, len(h.Old()))
+			}
+			results = append(results, result)
+			continue
+		}
+
+		old, replacement := trimmed.Old(), trimmed.New()
+		if level != Exact {
+			replacement = reindent(lines[at:at+len(old)], old, replacement)
+		}
+		lines = append(append(append([]string{}, lines[:at]...), replacement...), lines[at+len(old):]...)
+		delta += len(replacement) - len(old)
+		result.Line, result.Level = at+1, level
+		results = append(results, result)
+	}
+	return joinLines(lines, text), results
+}
+
+// locateHunk finds a hunk, dropping context lines at its edges when it does not match whole.
+// It returns the position and the hunk actually matched.
+func locateHunk(lines []string, h Hunk, hint int) (int, Hunk, Level, bool) {
+	if len(h.Old()) == 0 {
+		// Pure insertions go where the header says: after the line it names.
+		return min(max(hint+1, 0), len(lines)), h, Exact, true
+	}
+	if at, level, _, ok := Locate(lines, h.Old(), hint, 0); ok {
+		return at, h, level, true
+	}
+	for fuzz := 1; fuzz <= maxFuzz; fuzz++ {
+		trimmed, ok := trimContext(h, fuzz)
+		if !ok {
+			break
+		}
+		if at, _, _, ok := Locate(lines, trimmed.Old(), hint+fuzz, 0); ok {
+			return at, trimmed, Fuzzy, true
+		}
+	}
+	return 0, h, Exact, false
+}
+
+// trimContext drops up to n context lines from both ends of a hunk. It fails when there is
+// no context left to drop.
+func trimContext(h Hunk, n int) (Hunk, bool) {
+	start, end := 0, len(h.Lines)
+	for i := 0; i < n && start < end && h.Lines[start].Kind == Equal; i++ {
+		start++
+	}
+	for i := 0; i < n && end > start && h.Lines[end-1].Kind == Equal; i++ {
+		end--
+	}
+	if start == 0 && end == len(h.Lines) {
+		return h, false
+	}
+	trimmed := h
+	trimmed.Lines = h.Lines[start:end]
+	if len(trimmed.Old()) == 0 {
+		return h, false
+	}
+	return trimmed, true
+}
+
+// reindent adapts replacement lines written for want to the indentation actually found in got,
+// so that a block matched ignoring whitespace keeps the file's indentation.
+func reindent(got, want, replacement []string) []string {
+	fileIndent, wantIndent, ok := indents(got, want)
+	if !ok || fileIndent == wantIndent {
+		return replacement
+	}
+	result := make([]string, len(replacement))
+	for i, line := range replacement {
+		if rest, ok := strings.CutPrefix(line, wantIndent); ok && strings.TrimSpace(line) != "" {
+			result[i] = fileIndent + rest
+		} else {
+			result[i] = line
+		}
+	}
+	return result
+}
+
+// indents returns the leading whitespace of the first non-blank line of got and of want.
+func indents(got, want []string) (string, string, bool) {
+	for i := range want {
+		if i >= len(got) || strings.TrimSpace(want[i]) == "" {
+			continue
+		}
+		return leading(got[i]), leading(want[i]), true
+	}
+	return "", "", false
+}
+
+func leading(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// ReplaceBlock replaces the lines of search in text with replace: exactly when search occurs once
+// as whole lines, otherwise ignoring whitespace, re-indenting replace to match the file.
+func ReplaceBlock(text, search, replace string) (string, Level, error) {
+	lines, want := SplitLines(text), SplitLines(search)
+	if len(want) == 0 {
+		return "", Exact, fmt.Errorf("search text is empty")
+	}
+	at, n := -1, 0
+	for i := 0; i+len(want) <= len(lines); i++ {
+		if matchAt(lines, want, i, equalLine(Exact)) {
+			at = i
+			n++
+		}
+	}
+	switch {
+	case n == 1:
+		lines = append(append(append([]string{}, lines[:at]...), SplitLines(replace)...), lines[at+len(want):]...)
+		return joinLines(lines, text), Exact, nil
+	case n > 1:
+		return "", Exact, fmt.Errorf("search text matches %d places; include more surrounding lines", n)
+	}
+
+	at, _, unique, ok := Locate(lines, want, 0, 0)
+	if !ok {
+		line, matched := Closest(lines, want)
+		if matched == 0 {
+			return "", Exact, fmt.Errorf("search text not found")
+		}
+		return "", Exact, fmt.Errorf("search text not found; closest match at line %d (%d of %d lines)", line, matched, len(want))
+	}
+	if !unique {
+		return "", Exact, fmt.Errorf("search text matches several places when ignoring whitespace; include more surrounding lines")
+	}
+	replacement := reindent(lines[at:at+len(want)], want, SplitLines(replace))
+	lines = append(append(append([]string{}, lines[:at]...), replacement...), lines[at+len(want):]...)
+	return joinLines(lines, text), Whitespace, nil
+}
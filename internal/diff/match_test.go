@@ -0,0 +1,235 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestReplaceBlock(t *testing.T) {
+	tests := []struct {
+		name                  string
+		text, search, replace string
+		want                  string
+		level                 Level
+		err                   string
+	}{
+		{
+			name:    "exact",
+			text:    "a\nb\nc\n",
+			search:  "b\n",
+			replace: "B\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "replace without final newline",
+			text:    "a\nb\nc\n",
+			search:  "b\n",
+			replace: "B",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "file without final newline",
+			text:    "a\nb",
+			search:  "b\n",
+			replace: "B\n",
+			want:    "a\nB",
+		},
+		{
+			name:    "part of a line is not a match",
+			text:    "x := 1\nyx := 1\n",
+			search:  "x := 1\n",
+			replace: "x := 2\n",
+			want:    "x := 2\nyx := 1\n",
+		},
+		{
+			name:    "only part of a line",
+			text:    "total := count + 1\n",
+			search:  "count + 1",
+			replace: "count + 2",
+			err:     "search text not found",
+		},
+		{
+			name:    "ambiguous",
+			text:    "a\nb\na\nb\n",
+			search:  "a\nb\n",
+			replace: "c\n",
+			err:     "matches 2 places",
+		},
+		{
+			name:    "whitespace with the file's indentation",
+			text:    "func f() {\n\tif x {\n\t\treturn 1\n\t}\n}\n",
+			search:  "if x {\n\treturn 1\n}\n",
+			replace: "if x {\n\treturn 2\n}\n",
+			want:    "func f() {\n\tif x {\n\t\treturn 2\n\t}\n}\n",
+			level:   Whitespace,
+		},
+		{
+			name:    "ambiguous ignoring whitespace",
+			text:    "\tx++\n  x++\n",
+			search:  "x++\n",
+			replace: "x--\n",
+			err:     "matches several places when ignoring whitespace",
+		},
+		{
+			name:    "not found with a closest match",
+			text:    "a\nb\nc\nd\n",
+			search:  "b\nc\nx\n",
+			replace: "y\n",
+			err:     "closest match at line 2 (2 of 3 lines)",
+		},
+		{
+			name:    "empty search",
+			text:    "a\n",
+			search:  "",
+			replace: "b\n",
+			err:     "search text is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, level, err := ReplaceBlock(tt.text, tt.search, tt.replace)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || level != tt.level {
+				t.Errorf("got %q %s, want %q %s", got, level, tt.want, tt.level)
+			}
+		})
+	}
+}
+
+// hunk builds a hunk from lines prefixed with ' ', '-' or '+'.
+func hunk(oldStart int, lines ...string) Hunk {
+	h := Hunk{OldStart: oldStart, NewStart: oldStart}
+	for _, line := range lines {
+		kind := map[byte]Kind{' ': Equal, '-': Delete, '+': Insert}[line[0]]
+		h.Lines = append(h.Lines, Line{kind, line[1:]})
+		if kind != Insert {
+			h.OldLines++
+		}
+		if kind != Delete {
+			h.NewLines++
+		}
+	}
+	return h
+}
+
+func TestApplyHunks(t *testing.T) {
+	const text = "package p\n\nfunc f() int {\n\tx := 1\n\treturn x\n}\n"
+	tests := []struct {
+		name  string
+		hunks []Hunk
+		want  string
+		level Level
+		line  int
+		err   string
+	}{
+		{
+			name:  "exact",
+			hunks: []Hunk{hunk(4, " \tx := 1", "-\treturn x", "+\treturn x + 1")},
+			want:  "package p\n\nfunc f() int {\n\tx := 1\n\treturn x + 1\n}\n",
+			line:  4,
+		},
+		{
+			name:  "wrong line number",
+			hunks: []Hunk{hunk(40, " \tx := 1", "-\treturn x", "+\treturn x + 1")},
+			want:  "package p\n\nfunc f() int {\n\tx := 1\n\treturn x + 1\n}\n",
+			line:  4,
+		},
+		{
+			name:  "whitespace",
+			hunks: []Hunk{hunk(4, "     x := 1", "-    return x", "+    return x + 1")},
+			want:  "package p\n\nfunc f() int {\n\tx := 1\n\treturn x + 1\n}\n",
+			level: Whitespace,
+			line:  4,
+		},
+		{
+			name:  "fuzzy",
+			hunks: []Hunk{hunk(3, " func f() int {", " \tx := 1", "-\treturn x", "+\treturn x + 1", " }", " // end")},
+			want:  "package p\n\nfunc f() int {\n\tx := 1\n\treturn x + 1\n}\n",
+			level: Fuzzy,
+			line:  4,
+		},
+		{
+			name:  "insertion",
+			hunks: []Hunk{{OldStart: 1, NewStart: 2, NewLines: 1, Lines: []Line{{Insert, "// comment"}}}},
+			want:  "package p\n// comment\n\nfunc f() int {\n\tx := 1\n\treturn x\n}\n",
+			line:  2,
+		},
+		{
+			name:  "failed hunk report",
+			hunks: []Hunk{hunk(4, " \tx := 1", "-\treturn y", "+\treturn y + 1", " }")},
+			want:  text,
+			err:   "@@ -4,3 +4,3 @@ does not match the file; closest match at line 4 (2 of 3 lines)",
+		},
+		{
+			name:  "failed hunk without a close match",
+			hunks: []Hunk{hunk(4, "-nothing like it")},
+			want:  text,
+			err:   "@@ -4 +4,0 @@ does not match the file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, results := ApplyHunks(text, tt.hunks)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			r := results[0]
+			if tt.err != "" {
+				if r.Err == nil || r.Err.Error() != tt.err {
+					t.Errorf("err = %v, want %q", r.Err, tt.err)
+				}
+				return
+			}
+			if r.Err != nil || r.Level != tt.level || r.Line != tt.line {
+				t.Errorf("result = line %d %s %v, want line %d %s", r.Line, r.Level, r.Err, tt.line, tt.level)
+			}
+		})
+	}
+}
+
+func TestApplyHunksKeepsGoodHunks(t *testing.T) {
+	good := hunk(1, "-a", "+A")
+	bad := hunk(2, "-x", "+X")
+	got, results := ApplyHunks("a\nb\n", []Hunk{good, bad})
+	if got != "A\nb\n" {
+		t.Errorf("got %q", got)
+	}
+	if results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("errors = %v, %v", results[0].Err, results[1].Err)
+	}
+	if _, err := Apply("a\nb\n", []Hunk{good, bad}); err == nil || !strings.HasPrefix(err.Error(), "hunk 2: ") {
+		t.Errorf("Apply = %v", err)
+	}
+}
+
+// TestApplyHunksRoundTrip applies the hunks of random changes, with and without context,
+// insertions at the start and the end of the file included.
+func TestApplyHunksRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	random := func() string {
+		var sb strings.Builder
+		for range rng.Intn(15) {
+			sb.WriteString(string(rune('a'+rng.Intn(5))) + "\n")
+		}
+		return sb.String()
+	}
+	for i := 0; i < 2000; i++ {
+		old, new := random(), random()
+		for _, context := range []int{0, 3} {
+			hunks := Hunks(Lines(SplitLines(old), SplitLines(new)), context)
+			got, err := Apply(old, hunks)
+			if err != nil || got != new {
+				t.Fatalf("context %d: applying the hunks of %q -> %q gave %q, %v", context, old, new, got, err)
+			}
+		}
+	}
+}
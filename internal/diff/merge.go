@@ -0,0 +1,99 @@
+package diff
+
+import "strings"
+
+// Conflict markers written by Merge3, in diff3 style with the base between the two sides.
+const (
+	MarkerOurs   = "<<<<<<< current"
+	MarkerBase   = "||||||| grabbed"
+	MarkerSplit  = "======="
+	MarkerTheirs = ">>>>>>> model"
+)
+
+// Merge3 merges the changes from base to ours and from base to theirs, like diff3 -m.
+// Regions changed on one side only take that side; regions changed the same way on both
+// sides are taken once; other regions become conflicts, written with the markers above.
+// It returns the merged text and the number of conflicts.
+func Merge3(base, ours, theirs string) (string, int) {
+	b, o, t := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+	mo, mt := matches(b, o), matches(b, t)
+
+	var merged []string
+	conflicts := 0
+	i, j, k := 0, 0, 0
+	for {
+		// The next base line kept on both sides ends the current unstable region.
+		n := i
+		for n < len(b) && (mo[n] < 0 || mt[n] < 0) {
+			n++
+		}
+		oEnd, tEnd := len(o), len(t)
+		if n < len(b) {
+			oEnd, tEnd = mo[n], mt[n]
+		}
+
+		if n > i || oEnd > j || tEnd > k {
+			baseChunk, ourChunk, theirChunk := b[i:n], o[j:oEnd], t[k:tEnd]
+			switch {
+			case equalLines(ourChunk, baseChunk):
+				merged = append(merged, theirChunk...)
+			case equalLines(theirChunk, baseChunk), equalLines(ourChunk, theirChunk):
+				merged = append(merged, ourChunk...)
+			default:
+				conflicts++
+				merged = append(merged, MarkerOurs)
+				merged = append(merged, ourChunk...)
+				merged = append(merged, MarkerBase)
+				merged = append(merged, baseChunk...)
+				merged = append(merged, MarkerSplit)
+				merged = append(merged, theirChunk...)
+				merged = append(merged, MarkerTheirs)
+			}
+		}
+		if n == len(b) {
+			break
+		}
+		merged = append(merged, b[n])
+		i, j, k = n+1, oEnd+1, tEnd+1
+	}
+
+	// Keep the final newline unless both sides dropped it.
+	text := strings.Join(merged, "\n")
+	if len(merged) > 0 && (ours == "" || strings.HasSuffix(ours, "\n") || strings.HasSuffix(theirs, "\n")) {
+		text += "\n"
+	}
+	return text, conflicts
+}
+
+// matches maps every line of a to the index of the same line in b in their shortest edit
+// script, or -1 when it was deleted.
+func matches(a, b []string) []int {
+	m := make([]int, len(a))
+	i, j := 0, 0
+	for _, l := range Lines(a, b) {
+		switch l.Kind {
+		case Equal:
+			m[i] = j
+			i++
+			j++
+		case Delete:
+			m[i] = -1
+			i++
+		case Insert:
+			j++
+		}
+	}
+	return m
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
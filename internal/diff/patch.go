@@ -0,0 +1,227 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is the part of a unified diff that changes one file.
+type FileDiff struct {
+	// OldPath and NewPath are the names from the ---/+++ lines, "/dev/null" for created or deleted files.
+	OldPath, NewPath string
+	Hunks            []Hunk
+}
+
+// Path returns the path of the changed file without the a/ or b/ prefix git adds.
+func (f FileDiff) Path() string {
+	path := f.NewPath
+	if path == "/dev/null" {
+		path = f.OldPath
+	}
+	return StripPrefix(path)
+}
+
+// StripPrefix removes the a/ or b/ prefix of git diff paths.
+func StripPrefix(path string) string {
+	for _, prefix := range []string{"a/", "b/"} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			return rest
+		}
+	}
+	return path
+}
+
+// IsNew reports whether the diff creates the file.
+func (f FileDiff) IsNew() bool {
+	return f.OldPath == "/dev/null"
+}
+
+// IsDelete reports whether the diff deletes the file.
+func (f FileDiff) IsDelete() bool {
+	return f.NewPath == "/dev/null"
+}
+
+// hunkHeader matches "@@ -1,3 +1,4 @@" with optional counts and trailing section text.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// IsDiffStart reports whether lines[i] starts a unified diff: a "diff --git" line or a
+// "--- " line followed by a "+++ " line.
+func IsDiffStart(lines []string, i int) bool {
+	if strings.HasPrefix(lines[i], "diff --git ") {
+		return true
+	}
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// ParseUnified reads the unified diff starting at lines[0] and returns the file diffs and the number
+// of lines it spans. It is tolerant of the mistakes models make: hunk line counts are recomputed
+// from the hunk body, and "@@ ... @@" headers without numbers are accepted.
+func ParseUnified(lines []string) ([]FileDiff, int) {
+	var files []FileDiff
+	i := 0
+	for i < len(lines) {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "index "),
+			strings.HasPrefix(line, "new file mode"), strings.HasPrefix(line, "deleted file mode"),
+			strings.HasPrefix(line, "similarity index"), strings.HasPrefix(line, "rename "),
+			strings.HasPrefix(line, "old mode"), strings.HasPrefix(line, "new mode"):
+			i++
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			files = append(files, FileDiff{OldPath: diffName(line[4:]), NewPath: diffName(lines[i+1][4:])})
+			i += 2
+		case strings.HasPrefix(line, "@@") && len(files) > 0:
+			hunk, n := parseHunk(lines[i:])
+			current := &files[len(files)-1]
+			current.Hunks = append(current.Hunks, hunk)
+			i += n
+		default:
+			return files, i
+		}
+	}
+	return files, i
+}
+
+// diffName extracts the file name of a ---/+++ line, dropping a trailing timestamp.
+func diffName(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	return strings.Trim(strings.TrimSpace(s), `"`)
+}
+
+// parseHunk reads a hunk starting at its header and returns it with the number of lines read.
+func parseHunk(lines []string) (Hunk, int) {
+	var hunk Hunk
+	// oldWant and newWant are the line counts of the header, -1 when it has none.
+	oldWant, newWant := -1, -1
+	if m := hunkHeader.FindStringSubmatch(lines[0]); m != nil {
+		hunk.OldStart, _ = strconv.Atoi(m[1])
+		hunk.NewStart, _ = strconv.Atoi(m[3])
+		oldWant, newWant = 1, 1
+		if m[2] != "" {
+			oldWant, _ = strconv.Atoi(m[2])
+		}
+		if m[4] != "" {
+			newWant, _ = strconv.Atoi(m[4])
+		}
+	}
+	oldSeen, newSeen := 0, 0
+	// inBody reports whether lines[j] continues the hunk. A removed line starting with "-- "
+	// followed by an added one starting with "++ " reads like the header of the next file; the
+	// counts of the header, when it has them, tell them apart.
+	inBody := func(j int) bool {
+		if !isHunkLine(lines[j]) {
+			return false
+		}
+		if IsDiffStart(lines, j) {
+			return oldWant >= 0 && (oldSeen < oldWant || newSeen < newWant)
+		}
+		return true
+	}
+
+	i := 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			// Editors and chat UIs strip the space of empty context lines; keep them
+			// unless the hunk ends here.
+			if i+1 < len(lines) && inBody(i+1) {
+				hunk.Lines = append(hunk.Lines, Line{Equal, ""})
+				oldSeen++
+				newSeen++
+				continue
+			}
+			break
+		}
+		if !inBody(i) {
+			break
+		}
+		switch line[0] {
+		case ' ':
+			hunk.Lines = append(hunk.Lines, Line{Equal, line[1:]})
+			oldSeen++
+			newSeen++
+		case '-':
+			hunk.Lines = append(hunk.Lines, Line{Delete, line[1:]})
+			oldSeen++
+		case '+':
+			hunk.Lines = append(hunk.Lines, Line{Insert, line[1:]})
+			newSeen++
+		}
+	}
+
+	for _, l := range hunk.Lines {
+		if l.Kind != Insert {
+			hunk.OldLines++
+		}
+		if l.Kind != Delete {
+			hunk.NewLines++
+		}
+	}
+	return hunk, i
+}
+
+// isHunkLine reports whether line can belong to a hunk body. Whether a "--- " line is a removed
+// line or the start of the next file is up to the caller.
+func isHunkLine(line string) bool {
+	if line == "" {
+		return false
+	}
+	switch line[0] {
+	case ' ', '+', '-':
+		return true
+	case '\\':
+		// "\ No newline at end of file"
+		return true
+	}
+	return false
+}
+
+// Old returns the lines a hunk expects to find.
+func (h Hunk) Old() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l.Kind != Insert {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// New returns the lines a hunk leaves in place of Old.
+func (h Hunk) New() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l.Kind != Delete {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// Apply applies hunks to text, failing when any of them does not match. See ApplyHunks.
+func Apply(text string, hunks []Hunk) (string, error) {
+	patched, results := ApplyHunks(text, hunks)
+	for n, r := range results {
+		if r.Err != nil {
+			return "", fmt.Errorf("hunk %d: %v", n+1, r.Err)
+		}
+	}
+	return patched, nil
+}
+
+// joinLines joins lines with newlines, ending with one unless the original text had no
+// final newline.
+func joinLines(lines []string, original string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Join(lines, "\n")
+	if original == "" || strings.HasSuffix(original, "\n") {
+		text += "\n"
+	}
+	return text
+}
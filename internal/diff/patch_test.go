@@ -0,0 +1,123 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []FileDiff
+		// lines is how many lines the diff spans.
+		lines int
+	}{
+		{
+			name: "git diff",
+			diff: "diff --git a/main.go b/main.go\nindex 83db48f..bf269f4 100644\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n package main\n-var x = 1\n+var x = 2\n \n",
+			want: []FileDiff{{OldPath: "a/main.go", NewPath: "b/main.go", Hunks: []Hunk{{
+				OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3,
+				Lines: []Line{{Equal, "package main"}, {Delete, "var x = 1"}, {Insert, "var x = 2"}, {Equal, ""}},
+			}}}},
+			lines: 9,
+		},
+		{
+			name: "blank context line and wrong counts",
+			diff: "--- a.go\n+++ a.go\n@@ -1,9 +1,9 @@\n a\n\n-b\n+c\nthe end\n",
+			want: []FileDiff{{OldPath: "a.go", NewPath: "a.go", Hunks: []Hunk{{
+				OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3,
+				Lines: []Line{{Equal, "a"}, {Equal, ""}, {Delete, "b"}, {Insert, "c"}},
+			}}}},
+			lines: 7,
+		},
+		{
+			name: "header without numbers",
+			diff: "--- a/a.go\n+++ b/a.go\n@@ ... @@\n-b\n+c\n",
+			want: []FileDiff{{OldPath: "a/a.go", NewPath: "b/a.go", Hunks: []Hunk{{
+				OldLines: 1, NewLines: 1,
+				Lines: []Line{{Delete, "b"}, {Insert, "c"}},
+			}}}},
+			lines: 5,
+		},
+		{
+			name: "removed line starting with --",
+			diff: "--- a/q.sql\n+++ b/q.sql\n@@ -1,3 +1,3 @@\n select 1;\n--- old comment\n+++ new comment\n select 2;\n",
+			want: []FileDiff{{OldPath: "a/q.sql", NewPath: "b/q.sql", Hunks: []Hunk{{
+				OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3,
+				Lines: []Line{{Equal, "select 1;"}, {Delete, "-- old comment"}, {Insert, "++ new comment"}, {Equal, "select 2;"}},
+			}}}},
+			lines: 7,
+		},
+		{
+			name: "removed line starting with -- without a following ++ line",
+			diff: "--- a/q.sql\n+++ b/q.sql\n@@ ... @@\n--- old comment\n select 1;\n",
+			want: []FileDiff{{OldPath: "a/q.sql", NewPath: "b/q.sql", Hunks: []Hunk{{
+				OldLines: 2, NewLines: 1,
+				Lines: []Line{{Delete, "-- old comment"}, {Equal, "select 1;"}},
+			}}}},
+			lines: 5,
+		},
+		{
+			name: "two files",
+			diff: "--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+A\n--- a/b.go\n+++ b/b.go\n@@ -2,0 +3 @@\n+c\n",
+			want: []FileDiff{
+				{OldPath: "a/a.go", NewPath: "b/a.go", Hunks: []Hunk{{
+					OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+					Lines: []Line{{Delete, "a"}, {Insert, "A"}},
+				}}},
+				{OldPath: "a/b.go", NewPath: "b/b.go", Hunks: []Hunk{{
+					OldStart: 2, NewStart: 3, NewLines: 1,
+					Lines: []Line{{Insert, "c"}},
+				}}},
+			},
+			lines: 9,
+		},
+		{
+			name: "new file with a timestamp",
+			diff: "--- /dev/null\t2024-01-01 00:00:00\n+++ b/new.go\n@@ -0,0 +1,2 @@\n+package p\n+\nSome prose after the diff.\n",
+			want: []FileDiff{{OldPath: "/dev/null", NewPath: "b/new.go", Hunks: []Hunk{{
+				NewStart: 1, NewLines: 2,
+				Lines: []Line{{Insert, "package p"}, {Insert, ""}},
+			}}}},
+			lines: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(strings.TrimSuffix(tt.diff, "\n"), "\n")
+			if !IsDiffStart(lines, 0) {
+				t.Fatal("IsDiffStart = false")
+			}
+			got, n := ParseUnified(lines)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseUnified =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if n != tt.lines {
+				t.Errorf("spans %d lines, want %d", n, tt.lines)
+			}
+		})
+	}
+}
+
+func TestFileDiffPath(t *testing.T) {
+	tests := []struct {
+		diff         FileDiff
+		path         string
+		isNew, isDel bool
+	}{
+		{FileDiff{OldPath: "a/x.go", NewPath: "b/x.go"}, "x.go", false, false},
+		{FileDiff{OldPath: "/dev/null", NewPath: "b/dir/x.go"}, "dir/x.go", true, false},
+		{FileDiff{OldPath: "a/x.go", NewPath: "/dev/null"}, "x.go", false, true},
+		{FileDiff{OldPath: "x.go", NewPath: "x.go"}, "x.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.diff.Path(); got != tt.path {
+			t.Errorf("%+v: Path = %q, want %q", tt.diff, got, tt.path)
+		}
+		if tt.diff.IsNew() != tt.isNew || tt.diff.IsDelete() != tt.isDel {
+			t.Errorf("%+v: IsNew = %v, IsDelete = %v", tt.diff, tt.diff.IsNew(), tt.diff.IsDelete())
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package docbuilder
 
 import (
+	"agent/gorani/internal/journal"
 	"log"
 	"os"
 )
@@ -41,7 +42,7 @@ func BuildReadme() {
 	combined := string(introContent) + string(installContent) + "\n\n" + string(reqContent) + "\n\n" + string(roadmapContent)
 
 	// Write the combined content to README.md in the project root.
-	err = os.WriteFile("README.md", []byte(combined), 0644)
+	err = journal.WriteFile("README.md", []byte(combined), 0644)
 	if err != nil {
 		log.Fatalf("failed writing README.md: %v", err)
 	}
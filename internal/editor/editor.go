@@ -0,0 +1,165 @@
+package editor
+
+import (
+	"agent/gorani/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Scissors separates the text being edited from the help below it, like git's commit --cleanup=scissors.
+// Everything from this line down is removed, so Markdown headings above it are kept.
+const Scissors = "# ------------------------ >8 ------------------------"
+
+// ErrAborted is returned when the edited text is empty or unchanged.
+var ErrAborted = errors.New("aborted")
+
+// fallbacks are tried in order when neither the settings nor the environment name an editor.
+var fallbacks = []string{"nvim", "vim", "vi", "nano"}
+
+// Command returns the editor to run: the [editor] command from the user settings file, then
+// $VISUAL, then $EDITOR, then the first of nvim, vim, vi and nano found in PATH.
+// GUI editors need their wait flag, e.g. "code --wait". An editor in the project settings.toml is
+// ignored: it comes with the repository, and running it would let any cloned project run commands.
+func Command() (string, error) {
+	if project, err := config.Load(); err == nil && strings.TrimSpace(project.Editor.Command) != "" {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring [editor] command in %s; set it in the user settings file or $EDITOR\n", config.SettingsFile)
+	}
+	if settings, err := config.LoadUser(); err == nil && strings.TrimSpace(settings.Editor.Command) != "" {
+		return strings.TrimSpace(settings.Editor.Command), nil
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor, nil
+		}
+	}
+	candidates := fallbacks
+	if runtime.GOOS == "windows" {
+		candidates = []string{"notepad"}
+	}
+	for _, name := range candidates {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	path, err := config.UserSettingsPath()
+	if err != nil {
+		path = "the user settings file"
+	}
+	return "", fmt.Errorf("no editor found: set $EDITOR or [editor] command in %s", path)
+}
+
+// Open runs the editor on path and waits for it to exit.
+func Open(path string) error {
+	editor, err := Command()
+	if err != nil {
+		return err
+	}
+
+	// Like git, let the shell split the command so "code --wait" and quoted paths work.
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		fields := strings.Fields(editor)
+		cmd = exec.Command(fields[0], append(fields[1:], path)...)
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %q on %s: %v", editor, path, err)
+	}
+	return nil
+}
+
+// Options configures Edit.
+type Options struct {
+	// Initial is the text the buffer starts with.
+	Initial string
+	// Help is shown below the scissors line and removed afterwards.
+	Help string
+	// Pattern names the temporary file, e.g. "gorani-*.md" for Markdown highlighting.
+	Pattern string
+	// RequireChange aborts when the text is left as Initial.
+	RequireChange bool
+	// Raw returns the text exactly as written above the scissors line, for text such as diff
+	// hunks where blank lines and trailing whitespace matter.
+	Raw bool
+}
+
+// Edit opens a temporary file pre-filled with the initial text and help in the editor and
+// returns what the user wrote above the scissors line, trimmed unless Raw is set. It returns
+// ErrAborted when the result is blank, or unchanged while RequireChange is set.
+func Edit(opts Options) (string, error) {
+	pattern := opts.Pattern
+	if pattern == "" {
+		pattern = "gorani-*.md"
+	}
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	var buf strings.Builder
+	buf.WriteString(opts.Initial)
+	if opts.Initial != "" && !strings.HasSuffix(opts.Initial, "\n") {
+		buf.WriteByte('\n')
+	}
+	if !opts.Raw {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(Scissors + "\n")
+	buf.WriteString("# Do not modify or remove the line above.\n# Everything below it will be ignored.\n")
+	if opts.Help != "" {
+		for _, line := range strings.Split(strings.TrimRight(opts.Help, "\n"), "\n") {
+			buf.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+		}
+	}
+	if _, err := file.WriteString(buf.String()); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	if err := Open(path); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	text, initial := Cleanup(string(data)), Cleanup(opts.Initial)
+	if opts.Raw {
+		text, initial = cut(string(data)), cut(opts.Initial)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: the text is empty", ErrAborted)
+	}
+	if opts.RequireChange && text == initial {
+		return "", fmt.Errorf("%w: the text was not changed", ErrAborted)
+	}
+	return text, nil
+}
+
+// Cleanup removes the scissors line and everything below it, and surrounding blank lines.
+func Cleanup(text string) string {
+	return strings.Trim(cut(text), "\n \t")
+}
+
+// cut removes the scissors line and everything below it.
+func cut(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if i := strings.Index(text, Scissors); i >= 0 && (i == 0 || text[i-1] == '\n') {
+		text = text[:i]
+	}
+	return text
+}
@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommandIgnoresProjectEditor(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "env-editor")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.WriteFile("settings.toml", []byte("[editor]\ncommand = \"touch pwned\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := Command(); err != nil || got != "env-editor" {
+		t.Fatalf("Command = %q, %v, want $EDITOR", got, err)
+	}
+
+	userDir := filepath.Join(config, "gorani")
+	if err := os.MkdirAll(userDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userDir, "settings.toml"), []byte("[editor]\ncommand = \"code --wait\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Command(); err != nil || got != "code --wait" {
+		t.Fatalf("Command = %q, %v, want the user settings editor", got, err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package gocheck validates Go code written by a model before it reaches the working tree:
+// it parses each file, fixes its imports, formats it like gofmt and type-checks the packages
+// it belongs to with the pending files in place of the ones on disk.
+package gocheck
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Diagnostic is a problem found in a Go file.
+type Diagnostic struct {
+	// Path is relative to the root passed to Check.
+	Path         string
+	Line, Column int
+	Message      string
+	// Syntax is set for parse errors, which leave the file unformatted and unchecked.
+	Syntax bool
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.Path, d.Line, d.Column, d.Message)
+}
+
+// Result is the outcome of Check.
+type Result struct {
+	// Files holds the content to write for every Go file checked: formatted and with fixed
+	// imports, or as given when it does not parse.
+	Files map[string]string
+	// Notes describe the fixes made to each file, e.g. an added import.
+	Notes map[string][]string
+	// Diagnostics are the syntax and type errors, in file order.
+	Diagnostics []Diagnostic
+}
+
+// For returns the diagnostics of one file.
+func (r *Result) For(path string) []Diagnostic {
+	var found []Diagnostic
+	for _, d := range r.Diagnostics {
+		if d.Path == path {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// HasSyntaxErrors reports whether any file failed to parse.
+func (r *Result) HasSyntaxErrors() bool {
+	for _, d := range r.Diagnostics {
+		if d.Syntax {
+			return true
+		}
+	}
+	return false
+}
+
+// Check validates the pending content of Go files, keyed by their path relative to root.
+// Files that are not Go files are ignored. The packages of the files are type-checked against
+// the other files on disk, with the pending files replacing or adding to them.
+func Check(root string, files map[string]string) (*Result, error) {
+	l, err := newLoader(root)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Files: map[string]string{}, Notes: map[string][]string{}}
+
+	var paths []string
+	for path := range files {
+		if strings.HasSuffix(path, ".go") {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	// Parse everything first so that the imports of all files can be resolved in one go list call.
+	parsed := map[string]bool{}
+	for _, path := range paths {
+		src := files[path]
+		if _, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ParseComments); err != nil {
+			result.Diagnostics = append(result.Diagnostics, syntaxDiagnostics(path, err)...)
+			result.Files[path] = src
+			continue
+		}
+		l.overlay[l.abs(path)] = src
+		parsed[path] = true
+	}
+	l.prefetch()
+
+	broken := map[string]bool{}
+	for _, path := range paths {
+		if !parsed[path] {
+			broken[filepath.Dir(l.abs(path))] = true
+			continue
+		}
+		src := files[path]
+		fixed, notes := l.fixImports(l.abs(path), []byte(src))
+		formatted, err := format.Source(fixed)
+		if err != nil {
+			// The import fixes broke the file; keep it as written.
+			formatted, notes = []byte(src), nil
+		}
+		if string(formatted) != src && len(notes) == 0 {
+			notes = append(notes, "formatted with gofmt")
+		}
+		result.Files[path] = string(formatted)
+		result.Notes[path] = notes
+		l.overlay[l.abs(path)] = string(formatted)
+	}
+
+	// Type-check every package holding a pending file, with its tests when a test file changed.
+	l.resetUnits()
+	checked := map[string]bool{}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		dir := filepath.Dir(l.abs(path))
+		if !parsed[path] || broken[dir] {
+			continue
+		}
+		kinds := []unitKind{unitPackage}
+		if strings.HasSuffix(path, "_test.go") {
+			kinds = []unitKind{unitTest, unitExternalTest}
+		}
+		for _, kind := range kinds {
+			key := dir + "#" + string(kind)
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+			for _, e := range l.check(dir, kind) {
+				d := l.diagnostic(e)
+				if !seen[d.String()] {
+					seen[d.String()] = true
+					result.Diagnostics = append(result.Diagnostics, d)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// syntaxDiagnostics converts a parse error into diagnostics.
+func syntaxDiagnostics(path string, err error) []Diagnostic {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return []Diagnostic{{Path: path, Message: err.Error(), Syntax: true}}
+	}
+	var diagnostics []Diagnostic
+	for _, e := range list {
+		diagnostics = append(diagnostics, Diagnostic{
+			Path: path, Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg, Syntax: true,
+		})
+	}
+	return diagnostics
+}
+
+// diagnostic converts a type error into a diagnostic with a path relative to the root.
+func (l *loader) diagnostic(e types.Error) Diagnostic {
+	pos := e.Fset.Position(e.Pos)
+	return Diagnostic{Path: l.rel(pos.Filename), Line: pos.Line, Column: pos.Column, Message: e.Msg}
+}
@@ -0,0 +1,193 @@
+package gocheck
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// module is written to disk under every test: a main package and a package it may import.
+var module = map[string]string{
+	"go.mod":         "module example.com/shop\n\ngo 1.23\n",
+	"main.go":        "package main\n\nfunc main() {\n\tprintln(\"shop\")\n}\n",
+	"price/price.go": "package price\n\n// Discount returns the price lowered by percent.\nfunc Discount(price, percent int) int {\n\treturn price - price*percent/100\n}\n",
+}
+
+func writeModule(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range module {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCheckImports(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		src   string
+		want  string
+		notes []string
+	}{
+		{
+			name:  "adds standard import",
+			path:  "main.go",
+			src:   "package main\n\nfunc main() {\n\tfmt.Println(strings.ToUpper(\"shop\"))\n}\n",
+			want:  "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nfunc main() {\n\tfmt.Println(strings.ToUpper(\"shop\"))\n}\n",
+			notes: []string{`added import "fmt"`, `added import "strings"`},
+		},
+		{
+			name:  "adds to existing block",
+			path:  "main.go",
+			src:   "package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc main() {\n\tfmt.Println(strings.ToUpper(\"shop\"))\n}\n",
+			want:  "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nfunc main() {\n\tfmt.Println(strings.ToUpper(\"shop\"))\n}\n",
+			notes: []string{`added import "strings"`},
+		},
+		{
+			name:  "removes unused import",
+			path:  "main.go",
+			src:   "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfmt.Println(\"shop\")\n}\n",
+			want:  "package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc main() {\n\tfmt.Println(\"shop\")\n}\n",
+			notes: []string{`removed unused import "os"`},
+		},
+		{
+			name:  "removes last import",
+			path:  "main.go",
+			src:   "package main\n\nimport \"os\"\n\nfunc main() {\n\tprintln(\"shop\")\n}\n",
+			want:  "package main\n\nfunc main() {\n\tprintln(\"shop\")\n}\n",
+			notes: []string{`removed unused import "os"`},
+		},
+		{
+			name: "keeps blank import",
+			path: "main.go",
+			src:  "package main\n\nimport _ \"embed\"\n\nfunc main() {\n\tprintln(\"shop\")\n}\n",
+			want: "package main\n\nimport _ \"embed\"\n\nfunc main() {\n\tprintln(\"shop\")\n}\n",
+		},
+		{
+			name:  "adds module package",
+			path:  "main.go",
+			src:   "package main\n\nfunc main() {\n\tprintln(price.Discount(100, 10))\n}\n",
+			want:  "package main\n\nimport \"example.com/shop/price\"\n\nfunc main() {\n\tprintln(price.Discount(100, 10))\n}\n",
+			notes: []string{`added import "example.com/shop/price"`},
+		},
+		{
+			name:  "adds new module package",
+			path:  "cart/cart.go",
+			src:   "package cart\n\n// Total sums the discounted prices.\nfunc Total(prices []int) int {\n\ttotal := 0\n\tfor _, p := range prices {\n\t\ttotal += price.Discount(p, 10)\n\t}\n\treturn total\n}\n",
+			want:  "package cart\n\nimport \"example.com/shop/price\"\n\n// Total sums the discounted prices.\nfunc Total(prices []int) int {\n\ttotal := 0\n\tfor _, p := range prices {\n\t\ttotal += price.Discount(p, 10)\n\t}\n\treturn total\n}\n",
+			notes: []string{`added import "example.com/shop/price"`},
+		},
+		{
+			name: "does not shadow local name",
+			path: "main.go",
+			src:  "package main\n\ntype store struct{ Name string }\n\nfunc main() {\n\tvar strings store\n\tprintln(strings.Name)\n}\n",
+			want: "package main\n\ntype store struct{ Name string }\n\nfunc main() {\n\tvar strings store\n\tprintln(strings.Name)\n}\n",
+		},
+		{
+			name:  "formats",
+			path:  "main.go",
+			src:   "package main\nfunc main() {\nprintln(\"shop\")\n}\n",
+			want:  "package main\n\nfunc main() {\n\tprintln(\"shop\")\n}\n",
+			notes: []string{"formatted with gofmt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeModule(t)
+			result, err := Check(root, map[string]string{tt.path: tt.src})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.Files[tt.path]; got != tt.want {
+				t.Errorf("file =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !slices.Equal(result.Notes[tt.path], tt.notes) {
+				t.Errorf("notes = %q, want %q", result.Notes[tt.path], tt.notes)
+			}
+			if len(result.Diagnostics) > 0 {
+				t.Errorf("unexpected diagnostics: %v", result.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestCheckTypeErrors(t *testing.T) {
+	root := writeModule(t)
+	files := map[string]string{
+		"price/price.go": "package price\n\n// Discount returns the price lowered by percent.\nfunc Discount(price, percent int) string {\n\treturn price - price*percent/100\n}\n",
+		"main.go":        "package main\n\nfunc main() {\n\tprintln(price.Discount(100))\n}\n",
+		"README.md":      "not Go\n",
+	}
+	result, err := Check(root, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.HasSyntaxErrors() {
+		t.Fatalf("unexpected syntax errors: %v", result.Diagnostics)
+	}
+	if _, ok := result.Files["README.md"]; ok {
+		t.Error("README.md was checked")
+	}
+
+	// The caller is checked against the pending price package, not the one on disk.
+	want := map[string]string{
+		"price/price.go": "as string value in return statement",
+		"main.go":        "not enough arguments in call to price.Discount",
+	}
+	for path, message := range want {
+		found := result.For(path)
+		if len(found) != 1 || !strings.Contains(found[0].Message, message) {
+			t.Errorf("%s diagnostics = %v, want one containing %q", path, found, message)
+			continue
+		}
+		if found[0].Line == 0 || found[0].Path != path {
+			t.Errorf("%s diagnostic has no position: %+v", path, found[0])
+		}
+	}
+}
+
+func TestCheckPendingFilesTogether(t *testing.T) {
+	root := writeModule(t)
+	// A function added in one pending file is visible to the other, and to the caller.
+	files := map[string]string{
+		"price/round.go": "package price\n\n// Round rounds a price to a multiple of ten.\nfunc Round(price int) int {\n\treturn price / 10 * 10\n}\n",
+		"main.go":        "package main\n\nfunc main() {\n\tprintln(price.Round(price.Discount(99, 10)))\n}\n",
+	}
+	result, err := Check(root, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	if notes := result.Notes["main.go"]; !slices.Equal(notes, []string{`added import "example.com/shop/price"`}) {
+		t.Errorf("notes = %q", notes)
+	}
+}
+
+func TestCheckSyntaxError(t *testing.T) {
+	root := writeModule(t)
+	src := "package main\n\nfunc main() {\n\tprintln(\"shop\"\n}\n"
+	result, err := Check(root, map[string]string{"main.go": src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.HasSyntaxErrors() {
+		t.Fatalf("no syntax error reported: %v", result.Diagnostics)
+	}
+	if result.Files["main.go"] != src {
+		t.Errorf("file with a syntax error was changed:\n%s", result.Files["main.go"])
+	}
+	if d := result.For("main.go")[0]; d.Line != 4 {
+		t.Errorf("diagnostic = %v, want line 4", d)
+	}
+}
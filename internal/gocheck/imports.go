@@ -0,0 +1,422 @@
+package gocheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// textEdit replaces src[start:end] with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// fixImports removes the unused imports of a file and adds the missing ones, like goimports.
+// A missing import is added when exactly the selectors used with its name are exported by a
+// standard library package, a package of the module or a package the module already imports.
+func (l *loader) fixImports(path string, src []byte) ([]byte, []string) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return src, nil
+	}
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+
+	refs := l.unresolved(path, file)
+
+	// Map the name of every import to its spec; unused ones are removed.
+	imported := map[string]bool{}
+	var notes []string
+	removed := map[*ast.ImportSpec]bool{}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name, known := l.packageName(importPath)
+		if spec.Name != nil {
+			name, known = spec.Name.Name, true
+		}
+		imported[name] = true
+		if !known || name == "_" || name == "." || name == "C" || refs[name] != nil {
+			continue
+		}
+		removed[spec] = true
+		notes = append(notes, fmt.Sprintf("removed unused import %q", importPath))
+	}
+
+	var missing []string
+	for name := range refs {
+		if !imported[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	var added []string
+	for _, name := range missing {
+		if importPath, ok := l.resolve(name, refs[name], l.importPath(filepath.Dir(path))); ok {
+			added = append(added, importPath)
+			notes = append(notes, fmt.Sprintf("added import %q", importPath))
+		}
+	}
+
+	// New imports go into the first parenthesized import declaration, or into a new one
+	// replacing the single-line declarations.
+	var target *ast.GenDecl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() {
+			target = gen
+			break
+		}
+	}
+	var edits []textEdit
+	switch {
+	case len(added) > 0 && target != nil:
+		var text strings.Builder
+		for _, p := range added {
+			fmt.Fprintf(&text, "\t%q\n", p)
+		}
+		edits = append(edits, removeSpecs(src, file, removed, target, offset)...)
+		at := lineEnd(src, offset(target.Lparen))
+		edits = append(edits, textEdit{at, at, text.String()})
+	case len(added) > 0:
+		// Merge the single-line import declarations and the new imports into one block.
+		var specs []string
+		at := -1
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.IMPORT {
+				continue
+			}
+			start := lineStart(src, offset(gen.Pos()))
+			if at < 0 {
+				at = start
+			}
+			for _, spec := range gen.Specs {
+				if !removed[spec.(*ast.ImportSpec)] {
+					specs = append(specs, string(src[offset(spec.Pos()):offset(spec.End())]))
+				}
+			}
+			edits = append(edits, textEdit{start, lineEnd(src, offset(gen.End())), ""})
+		}
+		for _, p := range added {
+			specs = append(specs, strconv.Quote(p))
+		}
+		text := "import " + specs[0] + "\n"
+		if len(specs) > 1 {
+			text = "import (\n\t" + strings.Join(specs, "\n\t") + "\n)\n"
+		}
+		if at < 0 {
+			at, text = lineEnd(src, offset(file.Name.End())), "\n"+text
+		}
+		// Removals at the same offset are applied first, so the block replaces the first declaration.
+		edits = append(edits, textEdit{at, at, text})
+	default:
+		edits = append(edits, removeSpecs(src, file, removed, nil, offset)...)
+	}
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	// Apply from the end so offsets stay valid; at equal offsets insertions go after removals.
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	result := append([]byte(nil), src...)
+	for _, e := range edits {
+		result = append(result[:e.start], append([]byte(e.text), result[e.end:]...)...)
+	}
+	return result, notes
+}
+
+// removeSpecs deletes the lines of removed imports, and whole declarations left empty
+// unless they are keep.
+func removeSpecs(src []byte, file *ast.File, removed map[*ast.ImportSpec]bool, keep *ast.GenDecl, offset func(token.Pos) int) []textEdit {
+	var edits []textEdit
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		all := true
+		for _, spec := range gen.Specs {
+			if !removed[spec.(*ast.ImportSpec)] {
+				all = false
+			}
+		}
+		if all && gen != keep {
+			start := offset(gen.Pos())
+			if gen.Doc != nil {
+				start = offset(gen.Doc.Pos())
+			}
+			edits = append(edits, textEdit{lineStart(src, start), lineEnd(src, offset(gen.End())), ""})
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ImportSpec)
+			if !removed[spec] {
+				continue
+			}
+			start := offset(spec.Pos())
+			if spec.Doc != nil {
+				start = offset(spec.Doc.Pos())
+			}
+			edits = append(edits, textEdit{lineStart(src, start), lineEnd(src, offset(spec.End())), ""})
+		}
+	}
+	return edits
+}
+
+// lineStart returns the offset of the start of the line holding offset.
+func lineStart(src []byte, offset int) int {
+	for offset > 0 && src[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
+
+// lineEnd returns the offset just past the end of the line holding offset.
+func lineEnd(src []byte, offset int) int {
+	for offset < len(src) && src[offset] != '\n' {
+		offset++
+	}
+	if offset < len(src) {
+		offset++
+	}
+	return offset
+}
+
+// unresolved returns the names used as the X of a selector X.Sel that are declared neither in
+// the file nor at package level in its sibling files, with the selectors used on each.
+func (l *loader) unresolved(path string, file *ast.File) map[string]map[string]bool {
+	declared := l.packageDecls(path, file.Name.Name)
+	refs := map[string]map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		// The parser resolves names declared in the file; package names stay unresolved.
+		if !ok || x.Obj != nil || declared[x.Name] || types.Universe.Lookup(x.Name) != nil {
+			return true
+		}
+		if refs[x.Name] == nil {
+			refs[x.Name] = map[string]bool{}
+		}
+		refs[x.Name][sel.Sel.Name] = true
+		return true
+	})
+	return refs
+}
+
+// packageDecls returns the package-level names declared by the other files of a package.
+func (l *loader) packageDecls(path, pkgName string) map[string]bool {
+	declared := map[string]bool{}
+	dir := filepath.Dir(path)
+	for _, name := range l.goFiles(dir) {
+		sibling := filepath.Join(dir, name)
+		if sibling == path {
+			continue
+		}
+		src, err := l.source(sibling)
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), sibling, src, parser.SkipObjectResolution)
+		if err != nil || strings.TrimSuffix(file.Name.Name, "_test") != strings.TrimSuffix(pkgName, "_test") {
+			continue
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					declared[decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						declared[spec.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							declared[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return declared
+}
+
+// packageName returns the name of the package with the given import path and whether it is
+// certain rather than guessed from the path.
+func (l *loader) packageName(importPath string) (string, bool) {
+	if importPath == "C" {
+		return "C", true
+	}
+	if dir, ok := l.localDir(importPath); ok {
+		if name := l.clauseName(dir); name != "" {
+			return name, true
+		}
+		return guessName(importPath), false
+	}
+	if pkg, err := l.gc.Import(importPath); err == nil {
+		return pkg.Name(), true
+	}
+	return guessName(importPath), false
+}
+
+// clauseName reads the package name of a directory from its first non-test file.
+func (l *loader) clauseName(dir string) string {
+	for _, name := range l.goFiles(dir) {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := l.source(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if file, err := parser.ParseFile(token.NewFileSet(), name, src, parser.PackageClauseOnly); err == nil {
+			return file.Name.Name
+		}
+	}
+	return ""
+}
+
+// guessName derives a package name from an import path as goimports does:
+// "gopkg.in/yaml.v3" is yaml, "github.com/mattn/go-isatty" is isatty, ".../v2" is its parent.
+func guessName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexAny(name, ".-"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// resolve finds the package to import for name, exporting every selector in sels.
+// Among several candidates the shortest import path wins.
+func (l *loader) resolve(name string, sels map[string]bool, self string) (string, bool) {
+	var found []string
+	for _, candidate := range l.candidates(name) {
+		if candidate == self {
+			continue
+		}
+		pkg, err := l.Import(candidate)
+		if err != nil || pkg.Name() != name {
+			continue
+		}
+		ok := true
+		for sel := range sels {
+			if obj := pkg.Scope().Lookup(sel); obj == nil || !obj.Exported() {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			found = append(found, candidate)
+		}
+	}
+	if len(found) == 0 {
+		return "", false
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if len(found[i]) != len(found[j]) {
+			return len(found[i]) < len(found[j])
+		}
+		return found[i] < found[j]
+	})
+	return found[0], true
+}
+
+// candidates returns the import paths that may provide a package called name: the standard
+// library, the packages of the module and the packages its files already import.
+func (l *loader) candidates(name string) []string {
+	if l.index == nil {
+		l.buildIndex()
+	}
+	return l.index[name]
+}
+
+func (l *loader) buildIndex() {
+	l.index = map[string][]string{}
+	seen := map[string]bool{}
+	add := func(name, importPath string) {
+		if name != "" && !seen[importPath] {
+			seen[importPath] = true
+			l.index[name] = append(l.index[name], importPath)
+		}
+	}
+
+	cmd := exec.Command("go", "list", "std")
+	cmd.Dir = l.root
+	out, _ := cmd.Output()
+	std := map[string]bool{}
+	for _, importPath := range strings.Fields(string(out)) {
+		std[importPath] = true
+		if isInternal(importPath) {
+			continue
+		}
+		add(guessName(importPath), importPath)
+	}
+
+	if l.dir == "" {
+		return
+	}
+	filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			base := d.Name()
+			if path != l.dir && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") || base == "testdata" || base == "vendor" || base == "node_modules") {
+				return filepath.SkipDir
+			}
+			if path != l.dir {
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			if name := l.clauseName(path); name != "" && name != "main" {
+				add(name, l.importPath(path))
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			if _, local := l.localDir(importPath); local || std[importPath] || importPath == "C" {
+				continue
+			}
+			add(guessName(importPath), importPath)
+		}
+		return nil
+	})
+}
+
+// isInternal reports whether an import path cannot be imported from outside its tree.
+func isInternal(importPath string) bool {
+	for _, part := range strings.Split(importPath, "/") {
+		if part == "internal" || part == "vendor" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,340 @@
+package gocheck
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// unitKind selects the files of a directory that are type-checked together.
+type unitKind string
+
+const (
+	// unitPackage is the package without its tests, as imported by other packages.
+	unitPackage unitKind = "package"
+	// unitTest is the package with its in-package _test.go files.
+	unitTest unitKind = "test"
+	// unitExternalTest is the package_test package of a directory.
+	unitExternalTest unitKind = "xtest"
+)
+
+// unit is a type-checked set of files.
+type unit struct {
+	pkg     *types.Package
+	errs    []types.Error
+	loading bool
+}
+
+// loader type-checks the packages of a module from source, reading pending files from an
+// overlay instead of the disk. Packages outside the module come from compiled export data.
+type loader struct {
+	fset *token.FileSet
+	// root is the directory paths are relative to; dir and module locate the enclosing module.
+	root, dir, module string
+	goVersion         string
+	overlay           map[string]string
+	ctx               build.Context
+	units             map[string]*unit
+	exports           map[string]string
+	gc                types.Importer
+	std               []string
+	index             map[string][]string
+}
+
+func newLoader(root string) (*loader, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", root, err)
+	}
+	l := &loader{
+		fset:    token.NewFileSet(),
+		root:    abs,
+		overlay: map[string]string{},
+		units:   map[string]*unit{},
+		exports: map[string]string{},
+	}
+	l.dir, l.module, l.goVersion = findModule(abs)
+
+	l.ctx = build.Default
+	l.ctx.OpenFile = func(path string) (io.ReadCloser, error) {
+		if src, ok := l.overlay[path]; ok {
+			return io.NopCloser(strings.NewReader(src)), nil
+		}
+		return os.Open(path)
+	}
+	l.gc = importer.ForCompiler(l.fset, "gc", l.lookup)
+	return l, nil
+}
+
+// findModule walks up from dir to the nearest go.mod and returns its directory, module path
+// and go version. All are empty outside a module.
+func findModule(dir string) (string, string, string) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			var module, version string
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) == 2 && fields[0] == "module" {
+					module = strings.Trim(fields[1], `"`)
+				}
+				if len(fields) == 2 && fields[0] == "go" {
+					version = "go" + fields[1]
+				}
+			}
+			return dir, module, version
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", ""
+		}
+		dir = parent
+	}
+}
+
+func (l *loader) abs(path string) string {
+	return filepath.Join(l.root, filepath.FromSlash(path))
+}
+
+func (l *loader) rel(path string) string {
+	if rel, err := filepath.Rel(l.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// resetUnits forgets the packages checked so far, after the overlay changed.
+func (l *loader) resetUnits() {
+	l.units = map[string]*unit{}
+}
+
+// localDir returns the directory of an import path inside the module.
+func (l *loader) localDir(path string) (string, bool) {
+	if l.module == "" {
+		return "", false
+	}
+	if path == l.module {
+		return l.dir, true
+	}
+	if rest, ok := strings.CutPrefix(path, l.module+"/"); ok {
+		return filepath.Join(l.dir, filepath.FromSlash(rest)), true
+	}
+	return "", false
+}
+
+// importPath returns the import path of a directory inside the module.
+func (l *loader) importPath(dir string) string {
+	rel, err := filepath.Rel(l.dir, dir)
+	if l.module == "" || err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(dir)
+	}
+	if rel == "." {
+		return l.module
+	}
+	return l.module + "/" + filepath.ToSlash(rel)
+}
+
+// Import implements types.Importer.
+func (l *loader) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if dir, ok := l.localDir(path); ok {
+		u := l.load(dir, unitPackage)
+		if u.pkg == nil {
+			return nil, fmt.Errorf("no Go files in %s", l.rel(dir))
+		}
+		return u.pkg, nil
+	}
+	return l.gc.Import(path)
+}
+
+// lookup opens the export data of a package outside the module, found with go list.
+func (l *loader) lookup(path string) (io.ReadCloser, error) {
+	if _, ok := l.exports[path]; !ok {
+		l.list([]string{path})
+	}
+	file := l.exports[path]
+	if file == "" {
+		return nil, fmt.Errorf("cannot find export data of %s", path)
+	}
+	return os.Open(file)
+}
+
+// list runs go list -export for packages outside the module and remembers their export data.
+func (l *loader) list(paths []string) {
+	args := append([]string{"list", "-e", "-export", "-f", "{{.ImportPath}}\t{{.Export}}"}, paths...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = l.dir
+	if cmd.Dir == "" {
+		cmd.Dir = l.root
+	}
+	out, _ := cmd.Output()
+	for _, path := range paths {
+		l.exports[path] = ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if path, file, ok := strings.Cut(line, "\t"); ok {
+			l.exports[path] = file
+		}
+	}
+}
+
+// prefetch lists the imports of every overlay file outside the module with a single go list call.
+func (l *loader) prefetch() {
+	seen := map[string]bool{}
+	var paths []string
+	for path, src := range l.overlay {
+		file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range file.Imports {
+			imported := strings.Trim(spec.Path.Value, `"`)
+			if _, local := l.localDir(imported); local || imported == "C" || imported == "unsafe" || seen[imported] {
+				continue
+			}
+			seen[imported] = true
+			paths = append(paths, imported)
+		}
+	}
+	if len(paths) > 0 {
+		sort.Strings(paths)
+		l.list(paths)
+	}
+}
+
+// goFiles returns the names of the Go files of dir that match the build constraints,
+// including pending files not yet on disk.
+func (l *loader) goFiles(dir string) []string {
+	names := map[string]bool{}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") {
+				names[entry.Name()] = true
+			}
+		}
+	}
+	for path := range l.overlay {
+		if filepath.Dir(path) == dir {
+			names[filepath.Base(path)] = true
+		}
+	}
+
+	var matched []string
+	for name := range names {
+		if ok, err := l.ctx.MatchFile(dir, name); err == nil && ok {
+			matched = append(matched, name)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// source returns the pending content of a file, or its content on disk.
+func (l *loader) source(path string) ([]byte, error) {
+	if src, ok := l.overlay[path]; ok {
+		return []byte(src), nil
+	}
+	return os.ReadFile(path)
+}
+
+// parseDir parses the files of dir that belong to a unit.
+func (l *loader) parseDir(dir string, kind unitKind) []*ast.File {
+	var pkgName string
+	var files, tests, external []*ast.File
+	for _, name := range l.goFiles(dir) {
+		path := filepath.Join(dir, name)
+		src, err := l.source(path)
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(l.fset, path, src, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		isTest := strings.HasSuffix(name, "_test.go")
+		switch {
+		case !isTest:
+			if pkgName == "" {
+				pkgName = file.Name.Name
+			}
+			files = append(files, file)
+		case strings.HasSuffix(file.Name.Name, "_test"):
+			external = append(external, file)
+		default:
+			tests = append(tests, file)
+		}
+	}
+
+	switch kind {
+	case unitTest:
+		return append(files, tests...)
+	case unitExternalTest:
+		return external
+	}
+	return files
+}
+
+// load type-checks the files of a unit once.
+func (l *loader) load(dir string, kind unitKind) *unit {
+	key := dir + "#" + string(kind)
+	if u, ok := l.units[key]; ok {
+		return u
+	}
+	u := &unit{loading: true}
+	l.units[key] = u
+
+	files := l.parseDir(dir, kind)
+	if len(files) == 0 {
+		u.loading = false
+		return u
+	}
+	path := l.importPath(dir)
+	if kind == unitExternalTest {
+		path += "_test"
+	}
+	conf := types.Config{
+		Importer:    cycleGuard{l},
+		GoVersion:   l.goVersion,
+		FakeImportC: true,
+		Error: func(err error) {
+			if e, ok := err.(types.Error); ok {
+				u.errs = append(u.errs, e)
+			}
+		},
+	}
+	u.pkg, _ = conf.Check(path, l.fset, files, nil)
+	u.loading = false
+	return u
+}
+
+// check type-checks a unit and returns its errors.
+func (l *loader) check(dir string, kind unitKind) []types.Error {
+	return l.load(dir, kind).errs
+}
+
+// cycleGuard reports import cycles inside the module instead of recursing forever.
+type cycleGuard struct{ l *loader }
+
+func (g cycleGuard) Import(path string) (*types.Package, error) {
+	if dir, ok := g.l.localDir(path); ok {
+		if u, ok := g.l.units[dir+"#"+string(unitPackage)]; ok && u.loading {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+	}
+	return g.l.Import(path)
+}
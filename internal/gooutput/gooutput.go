@@ -0,0 +1,148 @@
+package gooutput
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind tells which step of the toolchain reported a failure.
+type Kind string
+
+const (
+	KindBuild   Kind = "build error"
+	KindVet     Kind = "vet"
+	KindTest    Kind = "test failure"
+	KindPanic   Kind = "panic"
+	KindTimeout Kind = "timeout"
+)
+
+// Failure is one problem reported by go build, go vet or go test.
+type Failure struct {
+	Kind Kind
+	// Package is the import path, when known.
+	Package string
+	// File is relative to the directory the command ran in, empty when unknown.
+	File   string
+	Line   int
+	Column int
+	// Message is the error, or the first message of a failing test.
+	Message string
+	// Test is the failing test, e.g. "TestParse/empty".
+	Test string
+	// Output is the rest of the test's output, such as further assertion messages.
+	Output string
+	// Stack is the trimmed goroutine stack of a panic.
+	Stack string
+}
+
+// Location returns file:line:col, or "" when the failure has no position.
+func (f Failure) Location() string {
+	switch {
+	case f.File == "":
+		return ""
+	case f.Line == 0:
+		return f.File
+	case f.Column == 0:
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+}
+
+// String returns the failure on one line.
+func (f Failure) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(f.Kind))
+	if f.Test != "" {
+		fmt.Fprintf(&sb, " in %s", f.Test)
+	}
+	if f.Package != "" {
+		fmt.Fprintf(&sb, " (%s)", f.Package)
+	}
+	if loc := f.Location(); loc != "" {
+		fmt.Fprintf(&sb, " at %s", loc)
+	}
+	if f.Message != "" {
+		fmt.Fprintf(&sb, ": %s", f.Message)
+	}
+	return sb.String()
+}
+
+func (f Failure) key() string {
+	return strings.Join([]string{string(f.Kind), f.Test, f.Location(), f.Message}, "\x00")
+}
+
+// Dedupe drops repeated failures, keeping the first of each. The same error is reported once
+// per package that fails to build because of it, and by both go build and go vet.
+func Dedupe(failures []Failure) []Failure {
+	seen := map[string]bool{}
+	var unique []Failure
+	for _, f := range failures {
+		k := f.key()
+		if f.Kind == KindVet || f.Kind == KindBuild {
+			// A type error found by both reads the same.
+			k = strings.Join([]string{f.Location(), f.Message}, "\x00")
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, f)
+	}
+	return unique
+}
+
+// positionRE matches "file.go:line:col: message" and "file.go:line: message".
+var positionRE = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// ParseBuild parses the text output of go build or go vet, with kind KindBuild or KindVet.
+// Continuation lines are joined to their error. Output that names no position, such as
+// module errors, becomes a single failure so that nothing is lost.
+func ParseBuild(kind Kind, output string) []Failure {
+	var failures []Failure
+	pkg := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# "):
+			// "# pkg" or "# [pkg]" heads the errors of a package.
+			pkg = strings.Trim(strings.TrimPrefix(line, "# "), "[]")
+			if name, _, ok := strings.Cut(pkg, " "); ok {
+				pkg = name
+			}
+		case positionRE.MatchString(line):
+			m := positionRE.FindStringSubmatch(line)
+			lineNo, _ := strconv.Atoi(m[2])
+			col, _ := strconv.Atoi(m[3])
+			failures = append(failures, Failure{
+				Kind:    kind,
+				Package: pkg,
+				File:    filepath.ToSlash(filepath.Clean(m[1])),
+				Line:    lineNo,
+				Column:  col,
+				Message: m[4],
+			})
+		case strings.HasPrefix(line, "\t") && len(failures) > 0:
+			// e.g. "\thave (int)\n\twant (string)".
+			failures[len(failures)-1].Message += "\n" + strings.TrimSpace(line)
+		}
+	}
+	if len(failures) == 0 && strings.TrimSpace(output) != "" {
+		failures = append(failures, Failure{Kind: kind, Package: pkg, Message: firstLines(strings.TrimSpace(output), 20)})
+	}
+	return failures
+}
+
+// firstLines returns up to n lines of text.
+func firstLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= n {
+		return text
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-n)
+}
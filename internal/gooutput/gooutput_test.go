@@ -0,0 +1,167 @@
+package gooutput
+
+import (
+	"agent/gorani/internal/runner"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// The output in testdata was captured from go build, go vet and go test -json run in
+// testdata/mod, with its absolute path replaced by $ROOT. test-plain.json and
+// test-plain.stderr are the same run as Go 1.23 and older print it, with build errors as
+// plain text on stderr instead of build-output events.
+
+// readCapture returns a captured output file with $ROOT replaced by root, or "" when it does
+// not exist.
+func readCapture(t *testing.T, root, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.ReplaceAll(string(data), "$ROOT", root)
+}
+
+// render writes failures and a summary in the form of the golden files.
+func render(failures []Failure, summary string) string {
+	var sb strings.Builder
+	for _, f := range failures {
+		fmt.Fprintf(&sb, "%s\n", f)
+		if f.Output != "" {
+			fmt.Fprintf(&sb, "  output:\n%s\n", indent(f.Output))
+		}
+		if f.Stack != "" {
+			fmt.Fprintf(&sb, "  stack:\n%s\n", indent(f.Stack))
+		}
+	}
+	if summary != "" {
+		fmt.Fprintf(&sb, "summary:\n%s", summary)
+	}
+	return sb.String()
+}
+
+// checkGolden compares got with testdata/name.golden, or rewrites it with -update.
+func checkGolden(t *testing.T, root, name, got string) {
+	t.Helper()
+	got = strings.ReplaceAll(got, root, "$ROOT")
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+func testRoot(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("testdata", "mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestParseBuildGolden(t *testing.T) {
+	root := testRoot(t)
+	for _, tt := range []struct {
+		name string
+		kind Kind
+	}{
+		{"build", KindBuild},
+		{"vet", KindVet},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := ParseBuild(tt.kind, readCapture(t, root, tt.name+".txt"))
+			checkGolden(t, root, tt.name, render(failures, ""))
+		})
+	}
+}
+
+func TestParseTestGolden(t *testing.T) {
+	root := testRoot(t)
+	for _, name := range []string{"test", "test-plain"} {
+		t.Run(name, func(t *testing.T) {
+			result := ParseTest(root, readCapture(t, root, name+".json"), readCapture(t, root, name+".stderr"))
+			checkGolden(t, root, name, render(result.Failures, result.Summary))
+		})
+	}
+}
+
+func TestReportGolden(t *testing.T) {
+	root := testRoot(t)
+	result := ParseTest(root, readCapture(t, root, "test.json"), "")
+	checkGolden(t, root, "report", Report(root, result.Failures))
+}
+
+func TestParseTestSuppressesFailedParents(t *testing.T) {
+	root := testRoot(t)
+	result := ParseTest(root, readCapture(t, root, "test.json"), "")
+	var tests []string
+	for _, f := range result.Failures {
+		if f.Test != "" {
+			tests = append(tests, f.Test)
+		}
+	}
+	if got, want := strings.Join(tests, " "), "TestAdd TestTable/positive TestLookup"; got != want {
+		t.Errorf("failed tests = %s, want %s", got, want)
+	}
+}
+
+func TestParseTestTrimsPanicStack(t *testing.T) {
+	root := testRoot(t)
+	result := ParseTest(root, readCapture(t, root, "test.json"), "")
+	for _, f := range result.Failures {
+		if f.Kind != KindPanic {
+			continue
+		}
+		if f.Location() != "calc/calc.go:12" {
+			t.Errorf("panic at %s, want calc/calc.go:12", f.Location())
+		}
+		for _, internal := range []string{"testing.tRunner", "runtime/panic.go"} {
+			if strings.Contains(f.Stack, internal) {
+				t.Errorf("stack keeps %q:\n%s", internal, f.Stack)
+			}
+		}
+		return
+	}
+	t.Fatal("no panic found")
+}
+
+func TestParse(t *testing.T) {
+	root := testRoot(t)
+	for _, tt := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"go", "build", "./..."}, true},
+		{[]string{"go", "vet", "./..."}, true},
+		{[]string{"go", "test", "-json", "./..."}, true},
+		{[]string{"go", "test", "./..."}, false},
+		{[]string{"make", "test"}, false},
+	} {
+		if _, ok := Parse(root, &runner.Result{Command: tt.args}); ok != tt.ok {
+			t.Errorf("Parse(%q) ok = %v, want %v", tt.args, ok, tt.ok)
+		}
+	}
+	failures, _ := Parse(root, &runner.Result{Command: []string{"go", "test", "-json", "./..."}, TimedOut: true, Duration: 1e9})
+	if len(failures) != 1 || failures[0].Kind != KindTimeout {
+		t.Errorf("timed out run = %v", failures)
+	}
+}
@@ -0,0 +1,107 @@
+package gooutput
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Limits keeping a report small enough to send back to the model.
+const (
+	maxReported    = 20
+	snippetContext = 3
+)
+
+// Report renders failures for a follow-up prompt: a numbered list of at most maxReported
+// failures with their test output and stacks, followed by the source around every position,
+// read from root.
+func Report(root string, failures []Failure) string {
+	if len(failures) == 0 {
+		return ""
+	}
+	shown := failures
+	if len(shown) > maxReported {
+		shown = shown[:maxReported]
+	}
+
+	var sb strings.Builder
+	for i, f := range shown {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, f)
+		if f.Output != "" {
+			fmt.Fprintf(&sb, "\n%s\n", indent(f.Output))
+		}
+		if f.Stack != "" {
+			fmt.Fprintf(&sb, "\n%s\n", indent(f.Stack))
+		}
+	}
+	if len(failures) > len(shown) {
+		fmt.Fprintf(&sb, "\n... and %d more failures.\n", len(failures)-len(shown))
+	}
+
+	if snippets := Snippets(root, shown); snippets != "" {
+		sb.WriteString("\nSource around the failures (lines marked with > are reported):\n\n")
+		sb.WriteString(snippets)
+	}
+	return sb.String()
+}
+
+// Snippets returns the source around the positions of the failures, a fenced block per file
+// with overlapping ranges merged. Files that cannot be read are left out.
+func Snippets(root string, failures []Failure) string {
+	marked := map[string]map[int]bool{}
+	var files []string
+	for _, f := range failures {
+		if f.File == "" || f.Line == 0 {
+			continue
+		}
+		if marked[f.File] == nil {
+			marked[f.File] = map[int]bool{}
+			files = append(files, f.File)
+		}
+		marked[f.File][f.Line] = true
+	}
+
+	var sb strings.Builder
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		var reported []int
+		for line := range marked[file] {
+			reported = append(reported, line)
+		}
+		sort.Ints(reported)
+
+		fmt.Fprintf(&sb, "%s\n```go\n", file)
+		last := 0
+		for _, line := range reported {
+			from, to := max(line-snippetContext, last+1), min(line+snippetContext, len(lines))
+			if last > 0 && from > last+1 {
+				sb.WriteString("...\n")
+			}
+			for n := from; n <= to; n++ {
+				marker := " "
+				if marked[file][n] {
+					marker = ">"
+				}
+				fmt.Fprintf(&sb, "%s%5d  %s\n", marker, n, lines[n-1])
+			}
+			last = max(last, to)
+		}
+		sb.WriteString("```\n\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// indent indents text by four spaces so it reads as a block under a list item.
+func indent(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n")
+}
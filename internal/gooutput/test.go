@@ -0,0 +1,265 @@
+package gooutput
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxOutputLines and maxStackLines bound the test output kept with a failure.
+const (
+	maxOutputLines = 40
+	maxStackLines  = 30
+)
+
+// event is a line of go test -json output, see go doc test2json.
+type event struct {
+	Action     string
+	Package    string
+	Test       string
+	Output     string
+	Elapsed    float64
+	ImportPath string
+}
+
+// testKey names a test, or a package when test is empty.
+type testKey struct{ pkg, test string }
+
+// TestResult is the parsed output of go test -json.
+type TestResult struct {
+	Failures []Failure
+	// Summary has a line per package, like go test prints without -json.
+	Summary string
+}
+
+var (
+	// messageRE matches the position t.Error and friends put before their message.
+	messageRE = regexp.MustCompile(`^\s*(\S+\.go):(\d+): (.*)$`)
+	// frameRE matches the file line of a stack frame: "\t/path/to/file.go:12 +0x1d".
+	frameRE = regexp.MustCompile(`^\t(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// ParseTest parses the stdout and stderr of go test -json run in root. Files named by test
+// messages and panic stacks are made relative to root. Build errors, whether reported as
+// build-output events or as plain text by older Go versions, become KindBuild failures.
+func ParseTest(root, stdout, stderr string) TestResult {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	outputs := map[testKey][]string{}
+	builds := map[string][]string{}
+	var failed []testKey
+	var packages []event
+	var plain []string
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var e event
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil {
+			plain = append(plain, line)
+			continue
+		}
+		switch e.Action {
+		case "output":
+			k := testKey{e.Package, e.Test}
+			outputs[k] = append(outputs[k], strings.TrimSuffix(e.Output, "\n"))
+		case "build-output":
+			pkg, _, _ := strings.Cut(e.ImportPath, " ")
+			builds[pkg] = append(builds[pkg], strings.TrimSuffix(e.Output, "\n"))
+		case "pass", "fail", "skip":
+			if e.Test == "" {
+				packages = append(packages, e)
+			} else if e.Action == "fail" {
+				failed = append(failed, testKey{e.Package, e.Test})
+			}
+		}
+	}
+
+	var result TestResult
+	failedIn := map[string]bool{}
+	for _, k := range failed {
+		if hasFailedSubtest(failed, k.pkg, k.test) {
+			// The parent of a failing subtest fails too, with nothing to add.
+			continue
+		}
+		failedIn[k.pkg] = true
+		f := testFailure(root, k.pkg, outputs[k])
+		f.Test = k.test
+		result.Failures = append(result.Failures, f)
+	}
+
+	var summary strings.Builder
+	for _, p := range packages {
+		switch {
+		case p.Action == "skip":
+			fmt.Fprintf(&summary, "?   \t%s\t[no test files]\n", p.Package)
+		case p.Action == "pass":
+			fmt.Fprintf(&summary, "ok  \t%s\t%.3fs\n", p.Package, p.Elapsed)
+		default:
+			fmt.Fprintf(&summary, "FAIL\t%s\t%.3fs\n", p.Package, p.Elapsed)
+			if failedIn[p.Package] {
+				continue
+			}
+			// The package failed without a failing test: it did not build, or TestMain or an
+			// init function failed.
+			if lines := builds[p.Package]; len(lines) > 0 {
+				result.Failures = append(result.Failures, ParseBuild(KindBuild, strings.Join(lines, "\n"))...)
+				continue
+			}
+			var lines []string
+			for _, line := range outputs[testKey{p.Package, ""}] {
+				if !strings.HasPrefix(line, "FAIL") && !strings.HasPrefix(line, "ok") {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) > 0 {
+				result.Failures = append(result.Failures, testFailure(root, p.Package, lines))
+			}
+		}
+	}
+	result.Summary = summary.String()
+
+	// Plain lines and stderr hold build errors of older Go versions and go command errors.
+	var text []string
+	for _, line := range append(plain, strings.Split(stderr, "\n")...) {
+		if strings.HasPrefix(line, "go: downloading") || strings.HasPrefix(line, "FAIL") || strings.HasPrefix(line, "ok") {
+			continue
+		}
+		text = append(text, line)
+	}
+	for _, f := range ParseBuild(KindBuild, strings.Join(text, "\n")) {
+		if f.File != "" || len(result.Failures) == 0 {
+			result.Failures = append(result.Failures, f)
+		}
+	}
+	result.Failures = Dedupe(result.Failures)
+	return result
+}
+
+func hasFailedSubtest(failed []testKey, pkg, test string) bool {
+	for _, k := range failed {
+		if k.pkg == pkg && strings.HasPrefix(k.test, test+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// testFailure builds the failure of a test, or of a package, from its output.
+func testFailure(root, pkg string, output []string) Failure {
+	f := Failure{Kind: KindTest, Package: pkg}
+	var rest []string
+	for i, line := range output {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "=== "), strings.HasPrefix(trimmed, "--- "), trimmed == "FAIL", trimmed == "":
+			continue
+		case strings.HasPrefix(line, "panic: "):
+			f.Kind = KindPanic
+			f.Message = strings.TrimPrefix(line, "panic: ")
+			f.Stack, f.File, f.Line = stack(root, output[i+1:])
+			return f
+		case f.Message == "" && messageRE.MatchString(line):
+			m := messageRE.FindStringSubmatch(line)
+			f.File = filepath.ToSlash(filepath.Join(PackageDir(root, pkg), m[1]))
+			f.Line, _ = strconv.Atoi(m[2])
+			f.Message = m[3]
+		default:
+			rest = append(rest, strings.TrimPrefix(line, "    "))
+		}
+	}
+	if f.Message == "" && len(rest) > 0 {
+		f.Message, rest = strings.TrimSpace(rest[0]), rest[1:]
+	}
+	if len(rest) > maxOutputLines {
+		rest = append(rest[:maxOutputLines], fmt.Sprintf("... (%d more lines)", len(rest)-maxOutputLines))
+	}
+	f.Output = strings.Join(rest, "\n")
+	return f
+}
+
+// stack trims the goroutine dump after a panic message to the frames outside the runtime and
+// the testing package, and finds the first frame in root, where the panic most likely is fixed.
+func stack(root string, lines []string) (string, string, int) {
+	var kept []string
+	file, line := "", 0
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") && !strings.HasPrefix(l, "\t") {
+			// A frame: the function, then its file and line.
+			if internalFrame(l) {
+				i++
+				continue
+			}
+			kept = append(kept, l, lines[i+1])
+			if m := frameRE.FindStringSubmatch(lines[i+1]); m != nil && file == "" {
+				if path, ok := inRoot(root, m[1]); ok && !strings.HasSuffix(path, "_testmain.go") {
+					file = path
+					line, _ = strconv.Atoi(m[2])
+				}
+			}
+			i++
+			continue
+		}
+		kept = append(kept, l)
+	}
+	if len(kept) > maxStackLines {
+		kept = append(kept[:maxStackLines:maxStackLines], fmt.Sprintf("... (%d more lines)", len(kept)-maxStackLines))
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n")), file, line
+}
+
+// internalFrame reports whether a stack frame belongs to the runtime or the test harness.
+func internalFrame(function string) bool {
+	for _, prefix := range []string{"runtime.", "testing.", "panic(", "created by testing."} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// inRoot returns path relative to root, reporting false when it is outside.
+func inRoot(root, path string) (string, bool) {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", false
+		}
+		path = rel
+	}
+	return filepath.ToSlash(path), true
+}
+
+var (
+	dirsMu sync.Mutex
+	dirs   = map[string]string{}
+)
+
+// PackageDir returns the directory of a package relative to root, looked up with go list
+// once per package. It returns "" when the package cannot be found.
+func PackageDir(root, pkg string) string {
+	dirsMu.Lock()
+	defer dirsMu.Unlock()
+	if dir, ok := dirs[pkg]; ok {
+		return dir
+	}
+	cmd := exec.Command("go", "list", "-find", "-f", "{{.Dir}}", pkg)
+	cmd.Dir = root
+	dir := ""
+	if out, err := cmd.Output(); err == nil {
+		if rel, err := filepath.Rel(root, strings.TrimSpace(string(out))); err == nil && !strings.HasPrefix(rel, "..") {
+			dir = rel
+		}
+	}
+	dirs[pkg] = dir
+	return dir
+}
@@ -0,0 +1,12 @@
+// Package broken does not compile.
+package broken
+
+// Name returns a name.
+func Name() string {
+	return 42
+}
+
+// Count returns a count.
+func Count() int {
+	return undefined
+}
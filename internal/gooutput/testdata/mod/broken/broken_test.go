@@ -0,0 +1,9 @@
+package broken
+
+import "testing"
+
+func TestName(t *testing.T) {
+	if Name() == "" {
+		t.Error("empty name")
+	}
+}
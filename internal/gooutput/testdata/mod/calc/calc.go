@@ -0,0 +1,14 @@
+// Package calc has failing tests whose go test -json output is captured in testdata.
+package calc
+
+// Add adds two numbers, wrongly.
+func Add(a, b int) int {
+	return a - b
+}
+
+// Lookup stores a key in a nil map, which panics.
+func Lookup(key string) int {
+	var m map[string]int
+	m[key] = 1
+	return m[key]
+}
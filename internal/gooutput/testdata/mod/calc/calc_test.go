@@ -0,0 +1,34 @@
+package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if got := Add(1, 2); got != 3 {
+		t.Errorf("Add(1, 2) = %d, want 3", got)
+		t.Log("a second line of output")
+	}
+}
+
+func TestTable(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		a, b, want int
+	}{
+		{"zero", 0, 0, 0},
+		{"positive", 2, 2, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Add(tt.a, tt.b); got != tt.want {
+				t.Fatalf("Add(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if Lookup("a") != 1 {
+		t.Error("Lookup failed")
+	}
+}
+
+func TestPasses(t *testing.T) {}
@@ -0,0 +1,9 @@
+// Package vetted builds but fails go vet.
+package vetted
+
+import "fmt"
+
+// Greet prints a greeting with a wrong verb.
+func Greet(name string) {
+	fmt.Printf("hello %d\n", name)
+}
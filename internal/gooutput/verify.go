@@ -0,0 +1,76 @@
+package gooutput
+
+import (
+	"agent/gorani/internal/runner"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TestOutputCap is the output kept of go test -json, which is parsed rather than shown and
+// is much more verbose than the runner's usual cap allows.
+const TestOutputCap = 16 * 1024 * 1024
+
+// Parse turns the result of a go build, go vet or go test -json command run by the runner
+// into failures. It reports false for other commands, whose output is left as it is.
+func Parse(root string, result *runner.Result) ([]Failure, bool) {
+	args := result.Command
+	if len(args) < 2 || args[0] != "go" {
+		return nil, false
+	}
+	if result.TimedOut {
+		return []Failure{{Kind: KindTimeout, Message: fmt.Sprintf("%s timed out after %s", strings.Join(args, " "), result.Duration.Round(time.Millisecond))}}, true
+	}
+	switch args[1] {
+	case "build", "install", "run":
+		if result.OK() {
+			return nil, true
+		}
+		return Dedupe(ParseBuild(KindBuild, result.Stderr+"\n"+result.Stdout)), true
+	case "vet":
+		if result.OK() {
+			return nil, true
+		}
+		return Dedupe(ParseBuild(KindVet, result.Stderr+"\n"+result.Stdout)), true
+	case "test":
+		for _, arg := range args[2:] {
+			if arg == "-json" {
+				if result.OK() {
+					return nil, true
+				}
+				return ParseTest(root, result.Stdout, result.Stderr).Failures, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Verify runs go build, go vet and go test -json on the packages matching pattern, stopping at
+// the first step that fails, and returns its failures. No failures means all steps passed.
+func Verify(ctx context.Context, r *runner.Runner, pattern string) ([]Failure, error) {
+	steps := [][]string{
+		{"go", "build", pattern},
+		{"go", "vet", pattern},
+		{"go", "test", "-json", pattern},
+	}
+	for _, step := range steps {
+		run := *r
+		if step[1] == "test" {
+			run.MaxOutput = max(run.MaxOutput, TestOutputCap)
+		}
+		result, err := run.Run(ctx, step...)
+		if err != nil {
+			return nil, err
+		}
+		failures, _ := Parse(r.Dir, result)
+		if !result.OK() && len(failures) == 0 {
+			// A failure the parser does not understand is still a failure.
+			failures = []Failure{{Kind: KindBuild, Message: firstLines(strings.TrimSpace(result.String()), 40)}}
+		}
+		if len(failures) > 0 {
+			return failures, nil
+		}
+	}
+	return nil, nil
+}
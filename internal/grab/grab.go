@@ -1,7 +1,7 @@
 package grab
 
 import (
-	"bufio"
+	"agent/gorani/internal/snapshot"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +16,9 @@ const maxFilesLimit = 200
 // Files that prevent grabbing when detected
 var protectedFiles = []string{".config", "ws_info.toml"}
 
+// writeClipboard copies text to the clipboard; tests replace it.
+var writeClipboard = clipboard.WriteAll
+
 // Grab auto-detects if the input is a file, directory, or just a filename
 func Grab(input string) error {
 	// Prevent grabbing home or root directory
@@ -110,19 +113,32 @@ func findFileByName(root string, filename string) (string, error) {
 	return foundPath, nil
 }
 
+// readGrabbed reads a file sent to the model and returns it as a ">>> path (base <hash>)" block,
+// recording a snapshot of it so that the answer can be merged with changes made to the file
+// before it is applied.
+func readGrabbed(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	hash, err := snapshot.Record(path, content)
+	if err != nil {
+		fmt.Println("Warning: snapshot not recorded:", err)
+		return fmt.Sprintf(">>> %s\n%s\n", path, content), nil
+	}
+	return fmt.Sprintf(">>> %s (base %s)\n%s\n", path, snapshot.Short(hash), content), nil
+}
+
 // GrabCode copies the content of a single file to the clipboard
 func GrabCode(filePath string) error {
 	// Read file contents
-	content, err := os.ReadFile(filePath)
+	clipboardContent, err := readGrabbed(filePath)
 	if err != nil {
 		return fmt.Errorf("error reading file %s: %v", filePath, err)
 	}
 
-	// Format content for clipboard
-	clipboardContent := fmt.Sprintf(">>> %s\n%s\n", filePath, string(content))
-
 	// Copy to clipboard
-	if err := clipboard.WriteAll(clipboardContent); err != nil {
+	if err := writeClipboard(clipboardContent); err != nil {
 		return fmt.Errorf("failed to copy file content to clipboard: %v", err)
 	}
 
@@ -137,7 +153,7 @@ func GrabCodesProject(root string) error {
 		return err
 	}
 
-	if err := clipboard.WriteAll(content); err != nil {
+	if err := writeClipboard(content); err != nil {
 		return fmt.Errorf("failed to copy to clipboard: %v", err)
 	}
 	fmt.Println("Copied all code files' contents to clipboard.")
@@ -164,12 +180,12 @@ func getCodesProjectContent(root string) (string, error) {
 					fmt.Println("Found code file:", path)
 
 					// Read file contents
-					content, readErr := os.ReadFile(path)
+					block, readErr := readGrabbed(path)
 					if readErr != nil {
 						fmt.Println("Error reading file:", path, readErr)
 					} else {
 						// Store formatted content
-						fileContents = append(fileContents, fmt.Sprintf(">>> %s\n%s\n", path, string(content)))
+						fileContents = append(fileContents, block)
 					}
 					// Once matched, no need to check further extensions
 					break
@@ -209,8 +225,7 @@ func countFiles(root string) int {
 
 // confirmAction prompts the user for confirmation before proceeding
 func confirmAction() bool {
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
+	response, _ := stdin.ReadString('\n')
 	response = strings.TrimSpace(strings.ToLower(response))
 	return response == "y" || response == "yes"
 }
@@ -230,13 +245,10 @@ func GrabFiles(filePaths []string) error {
 		}
 
 		// Read file contents
-		content, err := os.ReadFile(filePath)
+		formatted, err := readGrabbed(filePath)
 		if err != nil {
 			return fmt.Errorf("error reading file %s: %v", filePath, err)
 		}
-
-		// Format the content
-		formatted := fmt.Sprintf(">>> %s\n%s\n", filePath, string(content))
 		allContents = append(allContents, formatted)
 	}
 
@@ -244,7 +256,7 @@ func GrabFiles(filePaths []string) error {
 	combinedContent := strings.Join(allContents, "\n---\n")
 
 	// Copy to clipboard
-	if err := clipboard.WriteAll(combinedContent); err != nil {
+	if err := writeClipboard(combinedContent); err != nil {
 		return fmt.Errorf("failed to copy combined content to clipboard: %v", err)
 	}
 
@@ -252,6 +264,39 @@ func GrabFiles(filePaths []string) error {
 	return nil
 }
 
+// CollectContent gathers the formatted contents of the given files and folders
+// without touching the clipboard, in the same ">>> path" format the grab commands use.
+func CollectContent(paths []string) (string, error) {
+	var allContents []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("error: %s not found", path)
+		}
+
+		if info.IsDir() {
+			if isProtectedWorkspace(path) {
+				return "", fmt.Errorf("error: cannot grab workspace or protected directory %s", path)
+			}
+			folderContent, err := getCodesProjectContent(path)
+			if err != nil {
+				return "", err
+			}
+			allContents = append(allContents, folderContent)
+			continue
+		}
+
+		block, err := readGrabbed(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading file %s: %v", path, err)
+		}
+		allContents = append(allContents, block)
+	}
+
+	return strings.Join(allContents, "\n---\n"), nil
+}
+
 // GrabMultipleFolders accepts multiple folder paths, gathers code files from each, and writes the combined content to the clipboard.
 func GrabMultipleFolders(folders []string) error {
 	var allContents []string
@@ -299,7 +344,7 @@ func GrabMultipleFolders(folders []string) error {
 
 	// Combine all folder contents with a clear separator
 	combinedContent := strings.Join(allContents, "\n===\n")
-	if err := clipboard.WriteAll(combinedContent); err != nil {
+	if err := writeClipboard(combinedContent); err != nil {
 		return fmt.Errorf("failed to copy combined content to clipboard: %v", err)
 	}
 	fmt.Println("Copied content of multiple folders to clipboard.")
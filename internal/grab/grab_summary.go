@@ -9,8 +9,6 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
-
-	"github.com/atotto/clipboard"
 )
 
 // exprToString converts an AST expression into its string representation.
@@ -22,9 +20,9 @@ func exprToString(expr ast.Expr) string {
 	return buf.String()
 }
 
-// buildSummary walks through Go files under the provided root directory,
+// BuildSummary walks through Go files under the provided root directory,
 // and returns a summary of functions, structs, and interfaces along with their package info.
-func buildSummary(root string) (string, error) {
+func BuildSummary(root string) (string, error) {
 	fset := token.NewFileSet()
 	var summaryBuffer bytes.Buffer
 
@@ -92,13 +90,13 @@ func buildSummary(root string) (string, error) {
 // GrabSummary generates a summary of Go symbols from the provided root,
 // copies the summary to the clipboard, and prints a confirmation message.
 func GrabSummary(root string) error {
-	summary, err := buildSummary(root)
+	summary, err := BuildSummary(root)
 	if err != nil {
 		return err
 	}
 
 	// Copy the summary to the clipboard.
-	if err := clipboard.WriteAll(summary); err != nil {
+	if err := writeClipboard(summary); err != nil {
 		return fmt.Errorf("failed to copy summary to clipboard: %v", err)
 	}
 	fmt.Println("Summary copied to clipboard.")
@@ -2,73 +2,203 @@ package grab
 
 import (
 	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/templates"
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"unicode"
 )
 
+var (
+	// stdin is shared by every prompt of the package, so that input buffered while reading one
+	// answer is still there for the next.
+	stdin = bufio.NewReader(os.Stdin)
+	// interactive reports whether stdin is a terminal; tests replace it.
+	interactive = stdinIsTerminal
+)
+
+// SmartGrabOptions controls how SmartGrab collects its feature name and description.
+// Any field left empty is asked for interactively unless Yes is set.
+type SmartGrabOptions struct {
+	// Root is the folder whose Go symbols are summarized.
+	Root string
+	// Name is the feature name. Defaults to the current git branch.
+	Name string
+	// Description is the feature description.
+	Description string
+	// DescriptionFile is a file to read the description from ("-" reads stdin).
+	DescriptionFile string
+	// Yes disables every interactive prompt. A missing name is derived from the description;
+	// a missing description is an error.
+	Yes bool
+	// SaveArtifacts writes the prompt and answer to input.md and output.md.
+	SaveArtifacts bool
+}
+
 // SmartGrab generates a summary of Go symbols from the provided root,
-// determines the current git branch (if it's not "main" or "origin"),
-// builds a feature note, saves the combined prompt into input.md, and prints a confirmation.
+// resolves the feature name and description (from flags, a file, stdin or the terminal),
+// asks OpenAI which files are needed for the feature and grabs them.
+func SmartGrab(ctx context.Context, opts SmartGrabOptions) error {
+	if opts.Root == "" {
+		opts.Root = "./"
+	}
 
-func SmartGrab(root string) error {
-	featureBranch, err := getFeatureBranch()
+	featureName, err := resolveFeatureName(opts)
 	if err != nil {
 		return err
 	}
-	if featureBranch == "" {
-		// No valid branch was found (active branch is "main" or "origin", or none exists).
-		fmt.Println("No valid feature branch found. Aborting SmartGrab.")
-		return nil
+	description, err := resolveFeatureDescription(opts)
+	if err != nil {
+		return err
 	}
+	if featureName == "" {
+		if featureName = slug(description); featureName == "" {
+			return fmt.Errorf("no feature name: pass --name to set it")
+		}
+	}
+	fmt.Printf("Feature: %s\n", featureName)
 
-	grabPrompt, err := buildPrompt(featureBranch, root)
+	grabPrompt, err := buildPrompt(featureName, description, opts.Root)
 	if err != nil {
 		return err
 	}
 
-	// Save the prompt to input.md.
-	if err := os.WriteFile("input.md", []byte(grabPrompt), 0644); err != nil {
-		return fmt.Errorf("failed to save prompt to input.md: %v", err)
+	result, err := prompt.PromptOpenaiFiles(ctx, grabPrompt)
+	if err != nil {
+		return err
 	}
-	fmt.Println("Prompt saved to input.md.")
+	fmt.Println("Prompt sent to OpenAI.")
 
-	input, err := os.ReadFile("input.md")
-	if err != nil {
-		return fmt.Errorf("error reading input.md file: %w", err)
+	// Keep the prompt and answer around for inspection when asked to.
+	if opts.SaveArtifacts {
+		if err := prompt.SaveArtifacts(grabPrompt, result.Raw); err != nil {
+			return err
+		}
 	}
 
-	prompt.PromptOpenaiFiles(string(input)) // no return value
-	fmt.Println("Prompt sent to OpenAI.")
+	files := result.Value.Files
+	fmt.Println("Files suggested by OpenAI:", files)
 
-	// handle output
-	// read output.md
-	output, err := os.ReadFile("output.md")
+	// Pass the list of files to GrabFiles
+	if err := GrabFiles(files); err != nil {
+		return fmt.Errorf("error grabbing files: %w", err)
+	}
+
+	return nil
+}
+
+// resolveFeatureName returns the explicit feature name or falls back to the current git branch.
+// On "main", "origin" or outside a git repository the user is asked for a name instead, when
+// stdin is a terminal. With Yes it returns "", and the name is derived from the description.
+func resolveFeatureName(opts SmartGrabOptions) (string, error) {
+	if name := strings.TrimSpace(opts.Name); name != "" {
+		return name, nil
+	}
+
+	branch, err := getFeatureBranch()
 	if err != nil {
-		return fmt.Errorf("error reading output.md file: %w", err)
+		// Not being in a git repository is fine as long as we can ask for a name.
+		branch = ""
+	}
+	if branch != "" {
+		return branch, nil
 	}
 
-	// Define a struct that matches the output.md JSON structure
-	type Output struct {
-		Files []string `json:"files"`
+	if opts.Yes {
+		return "", nil
+	}
+	// Piped stdin holds the description, which must not be taken for the name.
+	if !interactive() {
+		return "", fmt.Errorf("no feature branch found: pass --name to set the feature name, or --yes to derive it from the description")
+	}
+	name, err := readLine("Please enter a feature name: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read feature name: %v", err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("no feature name provided")
 	}
+	return name, nil
+}
 
-	var parsedOutput Output
-	if err := json.Unmarshal(output, &parsedOutput); err != nil {
-		return fmt.Errorf("error parsing output.md JSON: %w", err)
+// resolveFeatureDescription returns the feature description from the options, a file, piped stdin,
+// or, as a last resort, an interactive prompt.
+func resolveFeatureDescription(opts SmartGrabOptions) (string, error) {
+	if description := strings.TrimSpace(opts.Description); description != "" {
+		return description, nil
 	}
 
-	fmt.Println("Files extracted from output.md:", parsedOutput.Files)
+	if opts.DescriptionFile != "" {
+		var data []byte
+		var err error
+		if opts.DescriptionFile == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(opts.DescriptionFile)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read feature description from %s: %v", opts.DescriptionFile, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
 
-	// Pass the list of files to GrabFiles
-	if err := GrabFiles(parsedOutput.Files); err != nil {
-		return fmt.Errorf("error grabbing files: %w", err)
+	// Piped input is used as the description without asking.
+	if !interactive() {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read feature description from stdin: %v", err)
+		}
+		if description := strings.TrimSpace(string(data)); description != "" {
+			return description, nil
+		}
 	}
 
-	return nil
+	if opts.Yes {
+		return "", fmt.Errorf("no feature description provided: pass --feature or --feature-file")
+	}
+	description, err := readLine("Please enter a detailed feature description: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read feature description: %v", err)
+	}
+	return description, nil
+}
+
+// maxSlugWords bounds the words of the description kept in a derived feature name.
+const maxSlugWords = 6
+
+// slug derives a feature name such as "add-a-discount-to-the" from the first words of a
+// description, lower-cased and joined by hyphens.
+func slug(description string) string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxSlugWords {
+		words = words[:maxSlugWords]
+	}
+	return strings.Join(words, "-")
+}
+
+// readLine prints the label and reads a single trimmed line from stdin.
+func readLine(label string) (string, error) {
+	fmt.Print(label)
+	line, err := stdin.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// stdinIsTerminal reports whether stdin is attached to an interactive terminal.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 // getFeatureBranch retrieves the active git branch and returns it if it is not "main" or "origin".
@@ -93,46 +223,30 @@ func getFeatureBranch() (string, error) {
 		}
 	}
 
-	// If no active branch is found, abort.
+	// If no active branch is found, there is no feature branch.
 	if currentBranch == "" {
-		fmt.Println("No active branch found. Aborting SmartGrab.")
+		fmt.Println("No active branch found.")
 		return "", nil
 	}
 
-	// Abort if the active branch is "main" or "origin".
+	// The "main" and "origin" branches are not feature branches.
 	if currentBranch == "main" || currentBranch == "origin" {
-		fmt.Printf("Active branch is '%s'. Aborting SmartGrab.\n", currentBranch)
+		fmt.Printf("Active branch is '%s', not a feature branch.\n", currentBranch)
 		return "", nil
 	}
 
 	return currentBranch, nil
 }
 
-// buildPrompt builds a prompt string using the feature branch name, a user-provided feature description,
-// and the code summary from the given root. It returns the combined prompt string or an error if the summary cannot be generated.
-func buildPrompt(featureBranch, root string) (string, error) {
-	// Print the feature branch.
-	fmt.Printf("Feature Branch: %s\n", featureBranch)
-
-	// Ask the user for a detailed feature description.
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Please enter a detailed feature description: ")
-	description, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("failed to read feature description: %v", err)
-	}
-	description = strings.TrimSpace(description)
-
+// buildPrompt renders the smartgrab prompt template with the feature name, the feature description,
+// and the code summary from the given root. It returns the prompt or an error if the summary cannot be generated.
+func buildPrompt(featureName, description, root string) (string, error) {
 	// Generate the code summary.
-	summary, err := buildSummary(root)
+	summary, err := BuildSummary(root)
 	if err != nil {
 		return "", err
 	}
 
-	// Build the combined prompt.
-	promptText := fmt.Sprintf("I want to build a feature called %s.\n", featureBranch)
-	promptText += "Feature Description:\n" + description + "\n\n"
-	promptText += "Here is the summary of the code:\n" + summary + "\n\n"
-	promptText += "Give me a list of files needed in order to build this feature."
-	return promptText, nil
+	data := templates.Data{Feature: featureName, Description: description, Summary: summary}
+	return templates.Render("smartgrab", data.WithGit())
 }
@@ -0,0 +1,115 @@
+package grab
+
+import (
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/prompt/fixturetest"
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// project is the repository the replayed requests were recorded in.
+var project = map[string]string{
+	"go.mod":           "module example.com/shop\n\ngo 1.23\n",
+	"main.go":          "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/shop/store\"\n)\n\nfunc main() {\n\ts := store.New()\n\ts.Put(\"apple\", 3)\n\tfmt.Println(s.Get(\"apple\"))\n}\n",
+	"store/store.go":   "package store\n\n// Store keeps item counts.\ntype Store struct {\n\titems map[string]int\n}\n\n// New returns an empty store.\nfunc New() *Store {\n\treturn &Store{items: map[string]int{}}\n}\n\n// Put sets the count of an item.\nfunc (s *Store) Put(name string, count int) {\n\ts.items[name] = count\n}\n\n// Get returns the count of an item.\nfunc (s *Store) Get(name string) int {\n\treturn s.items[name]\n}\n",
+	"report/report.go": "package report\n\n// Title returns the report title.\nfunc Title() string {\n\treturn \"Inventory\"\n}\n",
+}
+
+// captureClipboard records what is copied to the clipboard instead of copying it.
+func captureClipboard(t *testing.T) *string {
+	t.Helper()
+	var copied string
+	saved := writeClipboard
+	writeClipboard = func(text string) error {
+		copied = text
+		return nil
+	}
+	t.Cleanup(func() { writeClipboard = saved })
+	return &copied
+}
+
+func TestSmartGrabReplay(t *testing.T) {
+	fixturetest.Replay(t, project)
+	copied := captureClipboard(t)
+
+	err := SmartGrab(context.Background(), SmartGrabOptions{
+		Root:        ".",
+		Name:        "rename-get",
+		Description: "Rename Store.Get to Store.Count and update its callers.",
+		Yes:         true,
+	})
+	if err != nil {
+		t.Fatalf("SmartGrab: %v", err)
+	}
+	for _, header := range []string{">>> store/store.go (base ", ">>> main.go (base "} {
+		if !strings.Contains(*copied, header) {
+			t.Errorf("clipboard has no %q block:\n%s", header, *copied)
+		}
+	}
+	if strings.Contains(*copied, "report/report.go") {
+		t.Errorf("clipboard has a file the model did not ask for:\n%s", *copied)
+	}
+}
+
+func TestSmartGrabReplayUnrecorded(t *testing.T) {
+	fixturetest.Replay(t, project)
+	captureClipboard(t)
+
+	err := SmartGrab(context.Background(), SmartGrabOptions{
+		Root:        ".",
+		Name:        "unrecorded",
+		Description: "A feature nobody asked the model about.",
+		Yes:         true,
+	})
+	if !errors.Is(err, prompt.ErrNoFixture) {
+		t.Fatalf("SmartGrab = %v, want ErrNoFixture", err)
+	}
+}
+
+func TestPipedDescriptionIsNotTakenForName(t *testing.T) {
+	fixturetest.Replay(t, project)
+	oldStdin, oldInteractive := stdin, interactive
+	stdin = bufio.NewReader(strings.NewReader("add a discount to the store\n"))
+	interactive = func() bool { return false }
+	t.Cleanup(func() { stdin, interactive = oldStdin, oldInteractive })
+
+	if _, err := resolveFeatureName(SmartGrabOptions{}); err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Fatalf("resolveFeatureName error = %v, want one asking for --name", err)
+	}
+	description, err := resolveFeatureDescription(SmartGrabOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description != "add a discount to the store" {
+		t.Errorf("description = %q", description)
+	}
+}
+
+func TestYesDerivesNameFromDescription(t *testing.T) {
+	fixturetest.Replay(t, project)
+	captureClipboard(t)
+
+	// Outside git there is no branch to name the feature; the request is then made, and fails
+	// only because nobody recorded an answer to it.
+	err := SmartGrab(context.Background(), SmartGrabOptions{
+		Root:        ".",
+		Description: "A feature nobody asked the model about.",
+		Yes:         true,
+	})
+	if !errors.Is(err, prompt.ErrNoFixture) {
+		t.Fatalf("SmartGrab = %v, want ErrNoFixture", err)
+	}
+
+	for description, want := range map[string]string{
+		"Add a discount to Store.Put, and test it.": "add-a-discount-to-store-put",
+		"  Fix #42  ": "fix-42",
+		"?!":          "",
+	} {
+		if got := slug(description); got != want {
+			t.Errorf("slug(%q) = %q, want %q", description, got, want)
+		}
+	}
+}
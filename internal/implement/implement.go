@@ -1,10 +1,12 @@
 package implement
 
 import (
+	"agent/gorani/internal/journal"
 	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/templates"
 	"agent/gorani/internal/tree"
+	"context"
 	"fmt"
-	"os"
 	"os/exec"
 )
 
@@ -74,21 +76,28 @@ func MergeBranch(branchName string) error {
 	return nil
 }
 
-// PrepareImplementPrompt grabs the tree with functions output and writes a prompt to input.md.
-func PrepareImplementPrompt() error {
+// BuildImplementPrompt grabs the tree with functions output and returns the implementation prompt.
+func BuildImplementPrompt() (string, error) {
 	// Generate the plain-text tree including function details.
 	treeOutput, err := tree.GenerateTreeWithFunctionsString(".", "")
 	if err != nil {
-		return fmt.Errorf("failed to generate tree with functions: %v", err)
+		return "", fmt.Errorf("failed to generate tree with functions: %v", err)
 	}
 
-	// Prepare the prompt text.
-	promptText := "The following code structure with functions is provided:\n\n" +
-		treeOutput +
-		"\n\nPlease implement any missing functions or suggest improvements as needed."
+	// Render the implement prompt template.
+	data := templates.Data{Tree: treeOutput}
+	return templates.Render("implement", data.WithGit())
+}
+
+// PrepareImplementPrompt grabs the tree with functions output and writes a prompt to input.md.
+func PrepareImplementPrompt() error {
+	promptText, err := BuildImplementPrompt()
+	if err != nil {
+		return err
+	}
 
 	// Write the prompt text to input.md.
-	if err := os.WriteFile("input.md", []byte(promptText), 0644); err != nil {
+	if err := journal.WriteFile("input.md", []byte(promptText), 0644); err != nil {
 		return fmt.Errorf("failed to write prompt to input.md: %v", err)
 	}
 
@@ -96,19 +105,26 @@ func PrepareImplementPrompt() error {
 	return nil
 }
 
-func Implement() error {
-	PrepareImplementPrompt()
-
-	input, err := os.ReadFile("input.md")
+// Implement builds the implementation prompt, asks OpenAI which files need to change and returns them.
+// With saveArtifacts set, the prompt and answer are also written to input.md and output.md.
+func Implement(ctx context.Context, saveArtifacts bool) ([]string, error) {
+	promptText, err := BuildImplementPrompt()
 	if err != nil {
-		return fmt.Errorf("error reading input.md file: %w", err)
+		return nil, err
 	}
 
-	prompt.PromptOpenaiFiles(string(input)) // no return value
-
-	// handle outputfiles.
+	result, err := prompt.PromptOpenaiFiles(ctx, promptText)
+	if err != nil {
+		return nil, err
+	}
+	if saveArtifacts {
+		if err := prompt.SaveArtifacts(promptText, result.Raw); err != nil {
+			return nil, err
+		}
+	}
 
-	return nil
+	fmt.Println("Files to implement:", result.Value.Files)
+	return result.Value.Files, nil
 }
 
 // ImplementationManager defines an interface for branch management.
@@ -116,7 +132,7 @@ type ImplementationManager interface {
 	// CreateBranch creates a new branch.
 	CreateBranch(branchName string) error
 	// Implement performs the implementation tasks.
-	Implement() error
+	Implement(ctx context.Context, saveArtifacts bool) ([]string, error)
 	// MergeBranch merges the given branch into main.
 	MergeBranch(branchName string) error
 }
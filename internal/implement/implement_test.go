@@ -0,0 +1,27 @@
+package implement
+
+import (
+	"agent/gorani/internal/prompt/fixturetest"
+	"context"
+	"slices"
+	"testing"
+)
+
+// project is the repository the replayed requests were recorded in.
+var project = map[string]string{
+	"go.mod":         "module example.com/shop\n\ngo 1.23\n",
+	"main.go":        "package main\n\nimport \"example.com/shop/store\"\n\nfunc main() {\n\ts := store.New()\n\ts.Put(\"apple\", 3)\n}\n",
+	"store/store.go": "package store\n\n// Store keeps item counts.\ntype Store struct {\n\titems map[string]int\n}\n\n// New returns an empty store.\nfunc New() *Store {\n\treturn &Store{items: map[string]int{}}\n}\n\n// Put sets the count of an item.\nfunc (s *Store) Put(name string, count int) {\n\tpanic(\"not implemented\")\n}\n",
+}
+
+func TestImplementReplay(t *testing.T) {
+	fixturetest.Replay(t, project)
+
+	files, err := Implement(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Implement: %v", err)
+	}
+	if want := []string{"store/store.go"}; !slices.Equal(files, want) {
+		t.Errorf("Implement = %q, want %q", files, want)
+	}
+}
@@ -0,0 +1,172 @@
+package instructions
+
+import (
+	"agent/gorani/internal/config"
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the instructions file looked up at the repository root and in the user config directory.
+const FileName = "GORANI.md"
+
+// maxDepth bounds nested includes.
+const maxDepth = 8
+
+// includeLine matches an include directive on a line of its own, e.g. "@include docs/conventions.md".
+var includeLine = regexp.MustCompile(`^@include\s+(\S+)\s*$`)
+
+// Instructions is the combined text of the instructions files.
+type Instructions struct {
+	Text string
+	// Sources lists every file read, including included ones.
+	Sources []string
+}
+
+// Load reads the user-level instructions from ~/.config/gorani/GORANI.md and the project
+// instructions from GORANI.md at the repository root, project last so it can refine the user's.
+// Missing files are skipped; a missing include is an error.
+func Load() (*Instructions, error) {
+	user := ""
+	if dir, err := config.UserDir(); err == nil {
+		user = filepath.Join(dir, FileName)
+	}
+	return load(user, Root())
+}
+
+// LoadProject reads only the project instructions, leaving out the user's, which differ from
+// one machine to the next.
+func LoadProject() (*Instructions, error) {
+	return load("", Root())
+}
+
+// load combines the user instructions file, if any, with the one at the root of the project.
+// The user's file may include any file; the project's, which comes with the repository, only
+// files inside root.
+func load(user, root string) (*Instructions, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", root, err)
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	files := []struct{ path, confine string }{{user, ""}, {filepath.Join(root, FileName), root}}
+
+	result := &Instructions{}
+	var parts []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(file.path)
+		if err != nil || seen[abs] {
+			continue
+		}
+		if _, err := os.Stat(abs); os.IsNotExist(err) {
+			continue
+		}
+		text, err := expand(abs, file.confine, seen, nil, result)
+		if err != nil {
+			return nil, err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	result.Text = strings.Join(parts, "\n\n")
+	return result, nil
+}
+
+// Root returns the root of the git repository containing the current directory,
+// or the current directory outside a repository.
+func Root() string {
+	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "."
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// expand reads path and replaces include directives outside code fences with the included files,
+// resolved relative to the including file. Unless confine is empty, every file read must resolve
+// to a path inside it. stack holds the files being expanded to detect cycles.
+func expand(path, confine string, seen map[string]bool, stack []string, result *Instructions) (string, error) {
+	if confine != "" {
+		if err := checkInside(confine, path); err != nil {
+			if len(stack) > 0 {
+				return "", fmt.Errorf("%s: refusing to include %s: %v", stack[len(stack)-1], path, err)
+			}
+			return "", fmt.Errorf("refusing to read %s: %v", path, err)
+		}
+	}
+	for _, p := range stack {
+		if p == path {
+			return "", fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), path)
+		}
+	}
+	if len(stack) >= maxDepth {
+		return "", fmt.Errorf("includes nested deeper than %d levels at %s", maxDepth, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if len(stack) > 0 {
+			return "", fmt.Errorf("%s: failed to include %s: %v", stack[len(stack)-1], path, err)
+		}
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer file.Close()
+	seen[path] = true
+	result.Sources = append(result.Sources, path)
+	stack = append(stack, path)
+
+	var sb strings.Builder
+	inFence := false
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if match := includeLine.FindStringSubmatch(strings.TrimSpace(line)); match != nil && !inFence {
+			target := match[1]
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			included, err := expand(filepath.Clean(target), confine, seen, stack, result)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(strings.TrimRight(included, "\n"))
+			sb.WriteByte('\n')
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return sb.String(), nil
+}
+
+// checkInside fails when path, with its symlinks resolved, is not inside root. A path that does
+// not exist is left for the caller to report.
+func checkInside(root, path string) error {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("it is outside the repository %s", root)
+	}
+	return nil
+}
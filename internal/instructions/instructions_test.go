@@ -0,0 +1,68 @@
+package instructions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// layout creates a project directory next to a secret file outside it, and a user config directory.
+func layout(t *testing.T) (project, user string) {
+	t.Helper()
+	dir := t.TempDir()
+	project, user = filepath.Join(dir, "project"), filepath.Join(dir, "user")
+	for _, d := range []string{filepath.Join(project, "docs"), user} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(t, filepath.Join(dir, "secret"), "the secret\n")
+	write(t, filepath.Join(project, "docs", "conventions.md"), "Use tabs.\n")
+	return project, user
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProjectIncludes(t *testing.T) {
+	project, _ := layout(t)
+	write(t, filepath.Join(project, FileName), "Be brief.\n@include docs/conventions.md\n")
+	got, err := load("", project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Be brief.\nUse tabs." {
+		t.Errorf("Text = %q", got.Text)
+	}
+}
+
+func TestProjectIncludeOutsideIsRefused(t *testing.T) {
+	project, _ := layout(t)
+	if err := os.Symlink(filepath.Join(project, "..", "secret"), filepath.Join(project, "docs", "link.md")); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"../secret", filepath.Join(project, "..", "secret"), "docs/link.md"} {
+		write(t, filepath.Join(project, FileName), "@include "+target+"\n")
+		got, err := load("", project)
+		if err == nil || !strings.Contains(err.Error(), "outside the repository") {
+			t.Errorf("@include %s: got %+v, %v, want an error", target, got, err)
+		}
+	}
+}
+
+func TestUserIncludeOutside(t *testing.T) {
+	project, user := layout(t)
+	write(t, filepath.Join(user, FileName), "@include ../secret\n")
+	got, err := load(filepath.Join(user, FileName), project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "the secret" {
+		t.Errorf("Text = %q", got.Text)
+	}
+}
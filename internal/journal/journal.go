@@ -0,0 +1,395 @@
+package journal
+
+import (
+	"agent/gorani/internal/config"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRuns is the number of runs kept; older runs and the contents only they use are pruned.
+const maxRuns = 200
+
+// Entry records one file changed by a run.
+type Entry struct {
+	// Path is relative to the directory gorani runs in.
+	Path string `json:"path"`
+	// Before is the hash of the original content, empty when the file did not exist.
+	Before string      `json:"before,omitempty"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+	// After is the hash of the content last written, empty when the file was removed.
+	After string `json:"after,omitempty"`
+}
+
+// Run is one gorani invocation that wrote files.
+type Run struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Entries []Entry   `json:"entries"`
+	// Undoes is the run this run reverted, when it was made by gorani undo.
+	Undoes string `json:"undoes,omitempty"`
+	// UndoneBy is the run that reverted this one.
+	UndoneBy string `json:"undone_by,omitempty"`
+}
+
+// Dir returns the journal directory, .gorani/journal.
+func Dir() string {
+	return config.StatePath("journal")
+}
+
+func runPath(id string) string {
+	return filepath.Join(Dir(), "runs", id+".json")
+}
+
+func objectPath(hash string) string {
+	return filepath.Join(Dir(), "objects", hash)
+}
+
+var (
+	mu      sync.Mutex
+	command string
+	current *Run
+	onStart func(run *Run) error
+)
+
+// SetCommand names the command recorded with the run of this process, e.g. "gorani paste output.md".
+func SetCommand(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	command = name
+}
+
+// OnStart registers a function called before the first file of a run is written, e.g. to
+// checkpoint the working tree. Nothing is written when it fails.
+func OnStart(fn func(run *Run) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	onStart = fn
+}
+
+// Hash returns the hex SHA-256 of content.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteFile writes data to path like os.WriteFile, first saving the original content in the journal
+// so that the write can be undone. Nothing is written when the original cannot be saved.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	mu.Lock()
+	defer mu.Unlock()
+	entry, err := record(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	entry.After = Hash(data)
+	return save(current)
+}
+
+// Remove deletes path like os.Remove, first saving its content in the journal.
+func Remove(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	entry, err := record(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	entry.After = ""
+	return save(current)
+}
+
+// record returns the entry of path in the current run, saving its original content the first time.
+func record(path string) (*Entry, error) {
+	if current == nil {
+		now := time.Now()
+		run := &Run{
+			ID:      fmt.Sprintf("%s-%03d", now.Format("20060102-150405"), now.Nanosecond()/int(time.Millisecond)),
+			Time:    now,
+			Command: command,
+		}
+		if onStart != nil {
+			if err := onStart(run); err != nil {
+				return nil, err
+			}
+		}
+		current = run
+		prune()
+	}
+	key := filepath.ToSlash(filepath.Clean(path))
+	for i := range current.Entries {
+		if current.Entries[i].Path == key {
+			return &current.Entries[i], nil
+		}
+	}
+
+	entry := Entry{Path: key}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		entry.Before = Hash(data)
+		entry.After = entry.Before
+		if info, err := os.Stat(path); err == nil {
+			entry.Mode = info.Mode().Perm()
+		}
+		if err := storeObject(entry.Before, data); err != nil {
+			return nil, fmt.Errorf("failed to journal %s: %v", path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to journal %s: %v", path, err)
+	}
+	current.Entries = append(current.Entries, entry)
+	return &current.Entries[len(current.Entries)-1], nil
+}
+
+// storeObject saves content under its hash unless it is already there.
+func storeObject(hash string, content []byte) error {
+	path := objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readObject returns the content saved under hash.
+func readObject(hash string) ([]byte, error) {
+	data, err := os.ReadFile(objectPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("original content %s is missing from the journal: %v", hash[:12], err)
+	}
+	return data, nil
+}
+
+func save(run *Run) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := runPath(run.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save journal run %s: %v", run.ID, err)
+	}
+	return nil
+}
+
+// Runs returns the journaled runs, oldest first.
+func Runs() ([]*Run, error) {
+	entries, err := os.ReadDir(filepath.Join(Dir(), "runs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the journal: %v", err)
+	}
+	var runs []*Run
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		run, err := load(id)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	return runs, nil
+}
+
+// LastWrite returns the hash of the content gorani last wrote to path, "" when the journal has
+// no write of it or the last run removed it.
+func LastWrite(path string) string {
+	runs, err := Runs()
+	if err != nil {
+		return ""
+	}
+	key := filepath.ToSlash(filepath.Clean(path))
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, e := range runs[i].Entries {
+			if e.Path == key {
+				return e.After
+			}
+		}
+	}
+	return ""
+}
+
+func load(id string) (*Run, error) {
+	data, err := os.ReadFile(runPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal run %s: %v", id, err)
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse journal run %s: %v", id, err)
+	}
+	return &run, nil
+}
+
+// Find returns the run whose ID starts with prefix.
+func Find(prefix string) (*Run, error) {
+	runs, err := Runs()
+	if err != nil {
+		return nil, err
+	}
+	var found []*Run
+	for _, run := range runs {
+		if run.ID == prefix {
+			return run, nil
+		}
+		if strings.HasPrefix(run.ID, prefix) {
+			found = append(found, run)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no journal run %s: see 'gorani history'", prefix)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("run %s is ambiguous: it matches %d runs", prefix, len(found))
+}
+
+// Last returns the latest run that has not been undone and is not itself an undo.
+func Last() (*Run, error) {
+	runs, err := Runs()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].UndoneBy == "" && runs[i].Undoes == "" {
+			return runs[i], nil
+		}
+	}
+	return nil, errors.New("nothing to undo")
+}
+
+// Conflicts returns the files of a run whose content is no longer what the run left.
+func Conflicts(run *Run) []string {
+	var changed []string
+	for _, e := range run.Entries {
+		if currentHash(e.Path) != e.After {
+			changed = append(changed, e.Path)
+		}
+	}
+	return changed
+}
+
+// currentHash returns the hash of a file, or "" when it does not exist.
+func currentHash(path string) string {
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		return ""
+	}
+	return Hash(data)
+}
+
+// Undo restores the files of a run to their content before it, removing the files it created.
+// Files changed since the run are only overwritten with force. The restores are journaled as a
+// new run, which can be undone in turn. It returns the entries of the run that were restored.
+func Undo(run *Run, force bool) ([]Entry, error) {
+	if run.UndoneBy != "" {
+		return nil, fmt.Errorf("run %s was already undone by %s", run.ID, run.UndoneBy)
+	}
+	if changed := Conflicts(run); len(changed) > 0 && !force {
+		return nil, fmt.Errorf("files changed since run %s: %s (use --force to overwrite them)", run.ID, strings.Join(changed, ", "))
+	}
+
+	var restored []Entry
+	for i := len(run.Entries) - 1; i >= 0; i-- {
+		e := run.Entries[i]
+		path := filepath.FromSlash(e.Path)
+		var err error
+		if e.Before == "" {
+			if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+				continue
+			}
+			err = Remove(path)
+		} else {
+			var data []byte
+			if data, err = readObject(e.Before); err == nil {
+				if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+					err = WriteFile(path, data, e.Mode)
+				}
+				if err == nil && e.Mode != 0 {
+					err = os.Chmod(path, e.Mode)
+				}
+			}
+		}
+		if err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %v", e.Path, err)
+		}
+		restored = append(restored, e)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return restored, nil
+	}
+	current.Undoes = run.ID
+	if err := save(current); err != nil {
+		return restored, err
+	}
+	run.UndoneBy = current.ID
+	if undone, err := load(run.Undoes); run.Undoes != "" && err == nil {
+		// Undoing an undo redoes the original run, which can then be undone again.
+		undone.UndoneBy = ""
+		if err := save(undone); err != nil {
+			return restored, err
+		}
+	}
+	return restored, save(run)
+}
+
+// prune removes the oldest runs beyond maxRuns and the contents no run refers to anymore.
+// Failures are ignored: pruning only saves space.
+func prune() {
+	runs, err := Runs()
+	if err != nil || len(runs) < maxRuns {
+		return
+	}
+	for _, run := range runs[:len(runs)-maxRuns+1] {
+		os.Remove(runPath(run.ID))
+	}
+	used := map[string]bool{}
+	for _, run := range runs[len(runs)-maxRuns+1:] {
+		for _, e := range run.Entries {
+			used[e.Before] = true
+		}
+	}
+	objects, err := os.ReadDir(filepath.Join(Dir(), "objects"))
+	if err != nil {
+		return
+	}
+	for _, object := range objects {
+		if !used[object.Name()] {
+			os.Remove(objectPath(object.Name()))
+		}
+	}
+}
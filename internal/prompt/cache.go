@@ -0,0 +1,109 @@
+package prompt
+
+import (
+	"agent/gorani/internal/cache"
+	"agent/gorani/internal/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// cacheDisabled is set by DisableCache, e.g. for --no-cache.
+var cacheDisabled atomic.Bool
+
+// DisableCache turns the response cache off for the rest of the process.
+func DisableCache() {
+	cacheDisabled.Store(true)
+}
+
+// DefaultCache returns the response cache configured in settings.toml,
+// or nil when caching is disabled.
+func DefaultCache() *cache.Store {
+	if cacheDisabled.Load() {
+		return nil
+	}
+	settings, err := config.Load()
+	if err != nil || settings.Cache.Disabled {
+		return nil
+	}
+	return &cache.Store{
+		Dir:      config.StatePath("cache"),
+		TTL:      settings.Cache.TTL,
+		MaxBytes: settings.Cache.MaxMB << 20,
+	}
+}
+
+// cacheProvider answers repeated requests from a cache.
+// Only plain and structured requests are cached; streamed answers and tool calls
+// always go to the model.
+type cacheProvider struct {
+	next  Provider
+	store *cache.Store
+	// name and model identify the provider and its default model in the cache key.
+	name  string
+	model string
+}
+
+// WithCache wraps a provider so that identical requests are answered from store.
+func WithCache(next Provider, store *cache.Store, name, model string) Provider {
+	return cacheProvider{next: next, store: store, name: name, model: model}
+}
+
+func (p cacheProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if req.OnDelta != nil || len(req.Tools) > 0 {
+		return p.next.Complete(ctx, req)
+	}
+	key, err := p.key(req)
+	if err != nil {
+		return p.next.Complete(ctx, req)
+	}
+
+	if data, ok := p.store.Get(key); ok {
+		var resp Response
+		if err := json.Unmarshal(data, &resp); err == nil {
+			resp.Cached = true
+			return &resp, nil
+		}
+	}
+
+	resp, err := p.next.Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	// Answers that do not match the schema are re-prompted by Ask, so never replay them.
+	if req.Schema != nil && validateJSON(req.Schema.Definition, []byte(resp.Message.Content)) != nil {
+		return resp, nil
+	}
+	if data, err := json.Marshal(resp); err == nil {
+		if err := p.store.Put(key, data); err != nil {
+			fmt.Println("Warning: answer not cached:", err)
+		}
+	}
+	return resp, nil
+}
+
+// key hashes everything that determines the answer: provider, model, schema and messages.
+func (p cacheProvider) key(req *Request) (string, error) {
+	data, err := requestIdentity(p.name, p.model, req)
+	if err != nil {
+		return "", err
+	}
+	return cache.Key(data), nil
+}
+
+// requestIdentity serializes the parts of a request that determine its answer.
+// An empty request model stands for defaultModel.
+func requestIdentity(provider, defaultModel string, req *Request) ([]byte, error) {
+	model := req.Model
+	if model == "" {
+		model = defaultModel
+	}
+	return json.Marshal(struct {
+		Provider string     `json:"provider"`
+		Model    string     `json:"model"`
+		Schema   *Schema    `json:"schema,omitempty"`
+		Tools    []ToolSpec `json:"tools,omitempty"`
+		Messages []Message  `json:"messages"`
+	}{provider, model, req.Schema, req.Tools, req.Messages})
+}
@@ -0,0 +1,19 @@
+package prompt
+
+import (
+	"agent/gorani/internal/auth"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// newClient returns an OpenAI client using the resolved API key.
+// Credentials are only looked up when a request is about to be made.
+// Retries are handled by do, so the client's own retries are disabled.
+func newClient() (*openai.Client, error) {
+	credential, err := auth.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	return openai.NewClient(option.WithAPIKey(credential.APIKey), option.WithMaxRetries(0)), nil
+}
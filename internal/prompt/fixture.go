@@ -0,0 +1,145 @@
+package prompt
+
+import (
+	"agent/gorani/internal/cache"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables selecting the fixture mode.
+const (
+	// FixtureModeEnvVar is "record" to save every request and answer, or "replay" to answer
+	// from saved fixtures without calling the model.
+	FixtureModeEnvVar = "GORANI_FIXTURES"
+	// FixtureDirEnvVar overrides where fixtures are kept.
+	FixtureDirEnvVar = "GORANI_FIXTURE_DIR"
+)
+
+// Fixture modes.
+const (
+	FixtureRecord = "record"
+	FixtureReplay = "replay"
+)
+
+// DefaultFixtureDir is used when FixtureDirEnvVar is not set.
+const DefaultFixtureDir = "testdata/fixtures"
+
+// ErrNoFixture is returned in replay mode for a request that was never recorded.
+var ErrNoFixture = errors.New("no recorded fixture for request")
+
+// Fixture is a recorded request and its answer, stored as <dir>/<key>.json.
+type Fixture struct {
+	// Request is what identifies the answer: provider, model, schema, tools and messages.
+	Request  json.RawMessage `json:"request"`
+	Response *Response       `json:"response"`
+}
+
+// fixtureMode returns the fixture mode selected by the environment, or "" when fixtures are off.
+func fixtureMode() (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv(FixtureModeEnvVar)))
+	switch mode {
+	case "", "off":
+		return "", nil
+	case FixtureRecord, FixtureReplay:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid %s=%q: use record or replay", FixtureModeEnvVar, mode)
+}
+
+// fixtureDir returns the fixture directory selected by the environment.
+func fixtureDir() string {
+	if dir := strings.TrimSpace(os.Getenv(FixtureDirEnvVar)); dir != "" {
+		return dir
+	}
+	return DefaultFixtureDir
+}
+
+// fixtureProvider records answers of next into dir, or replays them when next is nil.
+type fixtureProvider struct {
+	next Provider
+	dir  string
+	// name and model identify the provider and its default model in fixture keys.
+	name  string
+	model string
+}
+
+// NewRecorder wraps a provider so that every request and answer is saved to dir.
+func NewRecorder(next Provider, dir, name, model string) Provider {
+	return fixtureProvider{next: next, dir: dir, name: name, model: model}
+}
+
+// NewReplayer returns a provider answering from the fixtures in dir. Requests without
+// a fixture fail with ErrNoFixture; streamed requests receive the whole answer as one delta.
+func NewReplayer(dir, name, model string) Provider {
+	return fixtureProvider{dir: dir, name: name, model: model}
+}
+
+func (p fixtureProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	identity, err := requestIdentity(p.name, p.model, req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(p.dir, cache.Key(identity)+".json")
+
+	if p.next == nil {
+		return p.replay(path, identity, req)
+	}
+
+	resp, err := p.next.Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	data, err := json.MarshalIndent(Fixture{Request: identity, Response: resp}, "", "  ")
+	if err != nil {
+		return resp, err
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return resp, fmt.Errorf("failed to create %s: %v", p.dir, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return resp, fmt.Errorf("failed to record fixture %s: %v", path, err)
+	}
+	return resp, nil
+}
+
+// replay serves the fixture at path.
+func (p fixtureProvider) replay(path string, identity []byte, req *Request) (*Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w %s (%s); record it with %s=%s",
+				ErrNoFixture, path, describeRequest(req), FixtureModeEnvVar, FixtureRecord)
+		}
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil || fixture.Response == nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+
+	resp := fixture.Response
+	if req.OnDelta != nil && resp.Message.Content != "" {
+		if err := req.OnDelta(resp.Message.Content); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// describeRequest summarizes a request for error messages by its last message.
+func describeRequest(req *Request) string {
+	if len(req.Messages) == 0 {
+		return "no messages"
+	}
+	last := req.Messages[len(req.Messages)-1]
+	content := strings.Join(strings.Fields(last.Content), " ")
+	if len(content) > 60 {
+		content = content[:57] + "..."
+	}
+	return fmt.Sprintf("%d messages, last %s: %q", len(req.Messages), last.Role, content)
+}
@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// staticProvider answers every request with the same text and counts the calls.
+type staticProvider struct {
+	answer string
+	calls  *int
+}
+
+func (p staticProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	*p.calls++
+	return &Response{Message: AssistantMessage(p.answer), Model: "test-model"}, nil
+}
+
+func TestFixtureRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	recorder := NewRecorder(staticProvider{"recorded answer", &calls}, dir, "openai", "test-model")
+	req := &Request{Messages: []Message{UserMessage("hello")}}
+	if _, err := recorder.Complete(context.Background(), req); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+
+	replayer := NewReplayer(dir, "openai", "test-model")
+	var streamed string
+	resp, err := replayer.Complete(context.Background(), &Request{
+		Messages: []Message{UserMessage("hello")},
+		OnDelta: func(delta string) error {
+			streamed += delta
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if resp.Message.Content != "recorded answer" || streamed != "recorded answer" {
+		t.Errorf("replayed %q, streamed %q", resp.Message.Content, streamed)
+	}
+	if calls != 1 {
+		t.Errorf("the model was called %d times, want 1", calls)
+	}
+
+	for name, req := range map[string]*Request{
+		"other message": {Messages: []Message{UserMessage("goodbye")}},
+		"other model":   {Model: "other-model", Messages: []Message{UserMessage("hello")}},
+	} {
+		if _, err := replayer.Complete(context.Background(), req); !errors.Is(err, ErrNoFixture) {
+			t.Errorf("%s: got %v, want ErrNoFixture", name, err)
+		}
+	}
+}
+
+func TestFixtureMode(t *testing.T) {
+	for value, want := range map[string]string{"": "", "off": "", "Replay": FixtureReplay, " record ": FixtureRecord} {
+		t.Setenv(FixtureModeEnvVar, value)
+		if mode, err := fixtureMode(); err != nil || mode != want {
+			t.Errorf("%s=%q: got %q, %v, want %q", FixtureModeEnvVar, value, mode, err, want)
+		}
+	}
+	t.Setenv(FixtureModeEnvVar, "replay-all")
+	if _, err := fixtureMode(); err == nil {
+		t.Errorf("%s=replay-all is accepted", FixtureModeEnvVar)
+	}
+}
@@ -0,0 +1,52 @@
+// Package fixturetest sets up tests that replay recorded model answers.
+package fixturetest
+
+import (
+	"agent/gorani/internal/prompt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Replay makes the default provider answer from the fixtures in the testdata/fixtures directory
+// of the calling package, then runs the test in a new directory holding the files of project,
+// keyed by slash-separated path. It returns that directory.
+//
+// A user-level GORANI.md is put in place to check that the fixtures do not depend on it.
+func Replay(t testing.TB, project map[string]string) string {
+	t.Helper()
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(prompt.FixtureModeEnvVar, prompt.FixtureReplay)
+	t.Setenv(prompt.FixtureDirEnvVar, fixtures)
+
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	write(t, filepath.Join(config, "gorani", "GORANI.md"), "Answer like a pirate.\n")
+
+	dir := t.TempDir()
+	for path, content := range project {
+		write(t, filepath.Join(dir, filepath.FromSlash(path)), content)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func write(t testing.TB, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,30 +1,41 @@
 package prompt
 
 import (
+	"agent/gorani/internal/editor"
+	"agent/gorani/internal/journal"
 	"fmt"
 	"os"
-	"os/exec"
 )
 
-// OpenInputInNeovim opens "input.md" in Neovim and returns the edited content.
-func OpenInputInNeovim() (string, error) {
-	filePath := "input.md"
+// InputFile keeps the last prompt, so it can be prepared by other commands and edited again.
+const InputFile = "input.md"
 
-	// Open input.md in Neovim
-	cmd := exec.Command("nvim", filePath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// inputTemplate pre-fills the editor when there is no prepared prompt.
+const inputTemplate = `## Task
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to open input.md in Neovim: %v", err)
+
+## Context
+`
+
+// inputHelp is shown below the scissors line of the editor.
+const inputHelp = `Write your prompt above in Markdown. Save and quit to send it to OpenAI.
+Leave it empty or unchanged to abort.`
+
+// EditInput opens the prompt in the configured editor and returns the edited text, which is also
+// saved to input.md. A prompt prepared in input.md is edited and may be sent unchanged;
+// otherwise the editor starts from a template that has to be filled in.
+func EditInput() (string, error) {
+	opts := editor.Options{Initial: inputTemplate, Help: inputHelp, RequireChange: true}
+	if data, err := os.ReadFile(InputFile); err == nil && len(data) > 0 {
+		opts = editor.Options{Initial: string(data), Help: inputHelp}
 	}
 
-	// Read the content after editing
-	content, err := os.ReadFile(filePath)
+	input, err := editor.Edit(opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to read input.md: %v", err)
+		return "", err
 	}
-
-	return string(content), nil
+	if err := journal.WriteFile(InputFile, []byte(input+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to save %s: %v", InputFile, err)
+	}
+	return input, nil
 }
@@ -0,0 +1,27 @@
+package prompt
+
+import "context"
+
+// instructionsProvider prepends the project instructions to every conversation as a system message.
+type instructionsProvider struct {
+	next Provider
+	text string
+}
+
+// WithInstructions wraps a provider so that every request starts with text as a system message.
+func WithInstructions(next Provider, text string) Provider {
+	if text == "" {
+		return next
+	}
+	return instructionsProvider{next: next, text: text}
+}
+
+func (p instructionsProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	withInstructions := *req
+	withInstructions.Messages = append([]Message{{Role: RoleSystem, Content: instructionsPreamble + p.text}}, req.Messages...)
+	return p.next.Complete(ctx, &withInstructions)
+}
+
+// instructionsPreamble introduces the instructions files to the model.
+const instructionsPreamble = "Follow these project instructions, including their coding conventions, " +
+	"forbidden patterns and test commands:\n\n"
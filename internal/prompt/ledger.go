@@ -0,0 +1,39 @@
+package prompt
+
+import (
+	"agent/gorani/internal/usage"
+	"context"
+	"time"
+)
+
+// usageProvider records every call of the wrapped provider in the usage ledger.
+type usageProvider struct {
+	next Provider
+}
+
+// WithUsage wraps a provider so that every call is recorded in .gorani/usage.jsonl.
+func WithUsage(next Provider) Provider {
+	return usageProvider{next: next}
+}
+
+func (p usageProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	start := time.Now()
+	resp, err := p.next.Complete(ctx, req)
+
+	record := usage.Record{Model: req.Model, LatencyMS: time.Since(start).Milliseconds()}
+	if resp != nil {
+		record.Model = resp.Model
+		record.Cached = resp.Cached
+		// A cached answer carries the usage of the call that produced it, but sent no tokens now.
+		if !resp.Cached {
+			record.PromptTokens = resp.Usage.PromptTokens
+			record.CompletionTokens = resp.Usage.CompletionTokens
+			record.TotalTokens = resp.Usage.TotalTokens
+		}
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	usage.Add(record)
+	return resp, err
+}
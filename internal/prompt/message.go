@@ -0,0 +1,107 @@
+package prompt
+
+import (
+	"agent/gorani/internal/config"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// Message roles understood by the chat API.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is a single turn of a conversation with the model.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the function calls requested by an assistant message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a tool message to the call it answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// UserMessage returns a message sent by the user.
+func UserMessage(content string) Message {
+	return Message{Role: RoleUser, Content: content}
+}
+
+// AssistantMessage returns a message written by the model.
+func AssistantMessage(content string) Message {
+	return Message{Role: RoleAssistant, Content: content}
+}
+
+// ToolMessage returns the result of the tool call with the given id.
+func ToolMessage(toolCallID, content string) Message {
+	return Message{Role: RoleTool, Content: content, ToolCallID: toolCallID}
+}
+
+// Usage counts the tokens consumed by one or more requests.
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// DefaultModel returns the model configured in settings.toml, falling back to config.DefaultModel.
+func DefaultModel() string {
+	settings, err := config.Load()
+	if err != nil {
+		fmt.Println("Warning:", err)
+		return config.DefaultModel
+	}
+	return settings.OpenAI.Model
+}
+
+// toParams converts messages to the OpenAI request representation.
+func toParams(messages []Message) []openai.ChatCompletionMessageParamUnion {
+	params := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	for _, m := range messages {
+		switch m.Role {
+		case RoleSystem:
+			params = append(params, openai.SystemMessage(m.Content))
+		case RoleAssistant:
+			if len(m.ToolCalls) == 0 {
+				params = append(params, openai.AssistantMessage(m.Content))
+				continue
+			}
+			calls := make([]openai.ChatCompletionMessageToolCallParam, 0, len(m.ToolCalls))
+			for _, call := range m.ToolCalls {
+				calls = append(calls, openai.ChatCompletionMessageToolCallParam{
+					ID:   openai.F(call.ID),
+					Type: openai.F(openai.ChatCompletionMessageToolCallTypeFunction),
+					Function: openai.F(openai.ChatCompletionMessageToolCallFunctionParam{
+						Name:      openai.F(call.Name),
+						Arguments: openai.F(call.Arguments),
+					}),
+				})
+			}
+			assistant := openai.ChatCompletionAssistantMessageParam{
+				Role:      openai.F(openai.ChatCompletionAssistantMessageParamRoleAssistant),
+				ToolCalls: openai.F(calls),
+			}
+			if m.Content != "" {
+				assistant.Content = openai.F([]openai.ChatCompletionAssistantMessageParamContentUnion{
+					openai.TextPart(m.Content),
+				})
+			}
+			params = append(params, assistant)
+		case RoleTool:
+			params = append(params, openai.ToolMessage(m.ToolCallID, m.Content))
+		default:
+			params = append(params, openai.UserMessage(m.Content))
+		}
+	}
+	return params
+}
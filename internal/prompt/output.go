@@ -1,11 +1,15 @@
 package prompt
 
 import (
+	"agent/gorani/internal/gocheck"
+	"agent/gorani/internal/journal"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 )
 
+// ProcessScriptsFromOutputFile reads a CodeResponse from output.md and applies it.
 func ProcessScriptsFromOutputFile() error {
 	filePath := "output.md"
 
@@ -21,15 +25,29 @@ func ProcessScriptsFromOutputFile() error {
 		return fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
 
-	// 3. Print the filename
+	return ApplyCodeResponse(codeResp)
+}
+
+// ApplyCodeResponse writes the first script of the response to its filename.
+// Go files are checked first: imports are fixed, the code is formatted and type errors are
+// reported; a file with syntax errors is not written.
+func ApplyCodeResponse(codeResp CodeResponse) error {
+	// Print the filename
 	fmt.Println("Filename from JSON:", codeResp.Filename)
 
-	// 4. Overwrite (or create) a new file with the content of the *first* script
+	// Overwrite (or create) a new file with the content of the *first* script
 	if len(codeResp.Scripts) > 0 {
 		firstScript := codeResp.Scripts[0]
+		if strings.HasSuffix(codeResp.Filename, ".go") {
+			checked, err := checkGo(codeResp.Filename, firstScript)
+			if err != nil {
+				return err
+			}
+			firstScript = checked
+		}
 
 		// Overwrite a file named by "Filename" with the script’s content
-		if err := os.WriteFile(codeResp.Filename, []byte(firstScript), 0644); err != nil {
+		if err := journal.WriteFile(codeResp.Filename, []byte(firstScript), 0644); err != nil {
 			return fmt.Errorf("failed to write file %q: %v", codeResp.Filename, err)
 		}
 		fmt.Printf("✅ Successfully wrote the first script to %q\n", codeResp.Filename)
@@ -39,3 +57,21 @@ func ProcessScriptsFromOutputFile() error {
 
 	return nil
 }
+
+// checkGo validates a Go file before it is written, printing the fixes and diagnostics.
+func checkGo(path, content string) (string, error) {
+	result, err := gocheck.Check(".", map[string]string{path: content})
+	if err != nil {
+		return "", err
+	}
+	for _, note := range result.Notes[path] {
+		fmt.Printf("note: %s: %s\n", path, note)
+	}
+	for _, d := range result.Diagnostics {
+		fmt.Println("✗", d)
+	}
+	if result.HasSyntaxErrors() {
+		return "", fmt.Errorf("%s has syntax errors and was not written", path)
+	}
+	return result.Files[path], nil
+}
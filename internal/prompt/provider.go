@@ -0,0 +1,258 @@
+package prompt
+
+import (
+	"agent/gorani/internal/instructions"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/shared"
+)
+
+// Request is a single completion request sent to a Provider.
+type Request struct {
+	// Model overrides the provider's default model when set.
+	Model    string
+	Messages []Message
+	// Schema requires a strict JSON answer matching the schema when set.
+	Schema *Schema
+	// Tools are the functions the model may call.
+	Tools []ToolSpec
+	// OnDelta streams the answer when set; it receives every piece of content as it arrives.
+	OnDelta func(delta string) error
+}
+
+// Schema names the JSON schema of a structured answer.
+type Schema struct {
+	Name        string
+	Description string
+	// Definition is the JSON schema, usually from GenerateSchema.
+	Definition interface{}
+}
+
+// Response is the answer of a Provider.
+type Response struct {
+	// Message is the assistant reply, including any tool calls.
+	Message Message
+	Usage   Usage
+	// Model is the model that answered.
+	Model string
+	// Cached is set when the answer came from the response cache.
+	Cached bool `json:"-"`
+}
+
+// Provider sends completion requests to a language model.
+type Provider interface {
+	Complete(ctx context.Context, req *Request) (*Response, error)
+}
+
+// DefaultProvider returns the provider used by the prompt functions, answering with the model
+// from settings.toml, adding the GORANI.md instructions, reusing cached answers and recording usage.
+// With GORANI_FIXTURES=replay answers come from recorded fixtures instead of OpenAI;
+// with GORANI_FIXTURES=record every answer is also saved as a fixture. The cache and the
+// user-level GORANI.md are left out in both modes so that fixtures match what the model was asked
+// on any machine.
+func DefaultProvider() Provider {
+	model := DefaultModel()
+	mode, err := fixtureMode()
+	if err != nil {
+		return failingProvider{err}
+	}
+
+	// Fixtures are shared through the repository, so they must not depend on the instructions
+	// in the user's own config directory.
+	load := instructions.Load
+	if mode != "" {
+		load = instructions.LoadProject
+	}
+	loaded, err := load()
+	if err != nil {
+		return failingProvider{err}
+	}
+
+	var provider Provider = NewOpenAIProvider(model)
+	switch mode {
+	case FixtureReplay:
+		provider = NewReplayer(fixtureDir(), "openai", model)
+	case FixtureRecord:
+		provider = NewRecorder(provider, fixtureDir(), "openai", model)
+	default:
+		if store := DefaultCache(); store != nil {
+			provider = WithCache(provider, store, "openai", model)
+		}
+	}
+	return WithUsage(WithInstructions(provider, loaded.Text))
+}
+
+// failingProvider fails every request, e.g. when the provider is misconfigured.
+type failingProvider struct {
+	err error
+}
+
+func (p failingProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	return nil, p.err
+}
+
+// OpenAIProvider sends requests to the OpenAI chat completions API.
+type OpenAIProvider struct {
+	// Model is used for requests that do not name one.
+	Model string
+	// Policy controls timeouts and retries.
+	Policy RetryPolicy
+}
+
+// NewOpenAIProvider returns an OpenAI provider for the given model using the configured retry policy.
+func NewOpenAIProvider(model string) *OpenAIProvider {
+	return &OpenAIProvider{Model: model, Policy: DefaultRetryPolicy()}
+}
+
+// Complete sends the request, retrying according to the provider's policy.
+// A streamed answer that breaks off after content was delivered is not retried;
+// the partial reply is returned along with the error.
+func (p *OpenAIProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	params, err := p.params(req)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.OnDelta == nil {
+		return do(ctx, p.Policy, func(ctx context.Context) (*Response, error) {
+			chat, err := client.Chat.Completions.New(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			if len(chat.Choices) == 0 {
+				return nil, ErrNoChoices
+			}
+			return &Response{
+				Message: replyMessage(chat.Choices[0].Message),
+				Usage:   usageOf(chat.Usage),
+				Model:   params.Model.Value,
+			}, nil
+		})
+	}
+
+	params.StreamOptions = openai.F(openai.ChatCompletionStreamOptionsParam{
+		IncludeUsage: openai.F(true),
+	})
+	var partial *Response
+	resp, err := do(ctx, p.Policy, func(ctx context.Context) (*Response, error) {
+		stream := client.Chat.Completions.NewStreaming(ctx, params)
+		defer stream.Close()
+
+		var content strings.Builder
+		var acc openai.ChatCompletionAccumulator
+		var usage Usage
+		fail := func(err error) (*Response, error) {
+			partial = &Response{Message: AssistantMessage(content.String()), Usage: usage, Model: params.Model.Value}
+			if content.Len() > 0 {
+				return nil, noRetry{err}
+			}
+			return nil, err
+		}
+
+		for stream.Next() {
+			chunk := stream.Current()
+			acc.AddChunk(chunk)
+			// The final chunk carries the usage of the whole request and no choices.
+			if chunk.Usage.TotalTokens > 0 {
+				usage = usageOf(chunk.Usage)
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+			delta := chunk.Choices[0].Delta.Content
+			content.WriteString(delta)
+			if err := req.OnDelta(delta); err != nil {
+				return fail(err)
+			}
+		}
+		if err := stream.Err(); err != nil {
+			return fail(err)
+		}
+
+		reply := AssistantMessage(content.String())
+		if len(acc.Choices) > 0 {
+			reply = replyMessage(acc.Choices[0].Message)
+		}
+		return &Response{Message: reply, Usage: usage, Model: params.Model.Value}, nil
+	})
+	if err != nil {
+		return partial, err
+	}
+	return resp, nil
+}
+
+// params converts a request to the OpenAI request parameters.
+func (p *OpenAIProvider) params(req *Request) (openai.ChatCompletionNewParams, error) {
+	model := req.Model
+	if model == "" {
+		model = p.Model
+	}
+	params := openai.ChatCompletionNewParams{
+		Messages: openai.F(toParams(req.Messages)),
+		Model:    openai.F(model),
+	}
+
+	if req.Schema != nil {
+		params.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
+			openai.ResponseFormatJSONSchemaParam{
+				Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+				JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        openai.F(req.Schema.Name),
+					Description: openai.F(req.Schema.Description),
+					Schema:      openai.F(req.Schema.Definition),
+					Strict:      openai.Bool(true),
+				}),
+			},
+		)
+	}
+
+	if len(req.Tools) > 0 {
+		toolParams := make([]openai.ChatCompletionToolParam, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			parameters, err := schemaToMap(tool.Parameters)
+			if err != nil {
+				return params, fmt.Errorf("invalid parameters for tool %s: %v", tool.Name, err)
+			}
+			toolParams = append(toolParams, openai.ChatCompletionToolParam{
+				Type: openai.F(openai.ChatCompletionToolTypeFunction),
+				Function: openai.F(shared.FunctionDefinitionParam{
+					Name:        openai.F(tool.Name),
+					Description: openai.F(tool.Description),
+					Parameters:  openai.F(parameters),
+					Strict:      openai.Bool(true),
+				}),
+			})
+		}
+		params.Tools = openai.F(toolParams)
+	}
+	return params, nil
+}
+
+// replyMessage converts an OpenAI answer to an assistant message.
+func replyMessage(m openai.ChatCompletionMessage) Message {
+	reply := AssistantMessage(m.Content)
+	for _, call := range m.ToolCalls {
+		reply.ToolCalls = append(reply.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return reply
+}
+
+// usageOf converts OpenAI token counts.
+func usageOf(u openai.CompletionUsage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
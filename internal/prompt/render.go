@@ -0,0 +1,273 @@
+package prompt
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// Colored formatters for rendered model answers.
+var (
+	headingColor = color.New(color.FgCyan, color.Bold)  // Markdown headings in cyan.
+	bulletColor  = color.New(color.FgYellow)            // List markers in yellow.
+	quoteColor   = color.New(color.Faint, color.Italic) // Block quotes dimmed.
+	inlineColor  = color.New(color.FgYellow)            // Inline `code` in yellow.
+	fenceColor   = color.New(color.Faint)               // Code fences dimmed.
+	keywordColor = color.New(color.FgMagenta)           // Keywords inside code blocks.
+	stringColor  = color.New(color.FgGreen)             // String literals inside code blocks.
+	commentColor = color.New(color.FgHiBlack)           // Comments inside code blocks.
+)
+
+// goKeywords are highlighted inside fenced code blocks.
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true,
+	"var": true, "nil": true, "true": true, "false": true,
+}
+
+// lineKind describes how the current Markdown line is rendered.
+type lineKind int
+
+const (
+	lineUndecided lineKind = iota
+	linePlain
+	lineHeading
+	lineBullet
+	lineQuote
+	lineFence
+)
+
+// MarkdownRenderer is an io.Writer that renders a Markdown stream to a terminal as it arrives.
+// Prose is written as soon as the style of its line is known; code block lines are written
+// once complete so they can be highlighted. Call Flush when the stream ends.
+type MarkdownRenderer struct {
+	w        io.Writer
+	carry    []byte // incomplete UTF-8 sequence from the previous write
+	pending  strings.Builder
+	kind     lineKind
+	inCode   bool
+	inInline bool
+}
+
+// NewMarkdownRenderer returns a renderer writing to w.
+func NewMarkdownRenderer(w io.Writer) *MarkdownRenderer {
+	return &MarkdownRenderer{w: w}
+}
+
+// Write renders the next chunk of the stream.
+func (r *MarkdownRenderer) Write(p []byte) (int, error) {
+	data := append(r.carry, p...)
+	r.carry = nil
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			r.carry = append([]byte(nil), data...)
+			break
+		}
+		ch, size := utf8.DecodeRune(data)
+		data = data[size:]
+		if err := r.writeRune(ch); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush renders any partially received line.
+func (r *MarkdownRenderer) Flush() error {
+	if len(r.carry) > 0 {
+		r.pending.Write(r.carry)
+		r.carry = nil
+	}
+	if r.pending.Len() == 0 {
+		return nil
+	}
+	err := r.emitLine()
+	r.reset()
+	return err
+}
+
+func (r *MarkdownRenderer) writeRune(ch rune) error {
+	if ch == '\n' {
+		if err := r.emitLine(); err != nil {
+			return err
+		}
+		r.reset()
+		_, err := io.WriteString(r.w, "\n")
+		return err
+	}
+
+	// Code lines and undecided prose are buffered.
+	if r.inCode || r.kind == lineUndecided || r.kind == lineFence {
+		r.pending.WriteRune(ch)
+		if !r.inCode && r.kind == lineUndecided {
+			r.kind = classifyLine(r.pending.String())
+			if r.kind != lineUndecided && r.kind != lineFence {
+				text := r.pending.String()
+				r.pending.Reset()
+				return r.emitProse(text)
+			}
+		}
+		return nil
+	}
+	return r.emitProse(string(ch))
+}
+
+// emitLine writes whatever is buffered for the current line.
+func (r *MarkdownRenderer) emitLine() error {
+	text := r.pending.String()
+	r.pending.Reset()
+
+	if strings.HasPrefix(strings.TrimSpace(text), "```") {
+		r.inCode = !r.inCode
+		_, err := fenceColor.Fprint(r.w, text)
+		return err
+	}
+	if r.inCode {
+		_, err := io.WriteString(r.w, highlightCode(text))
+		return err
+	}
+	if r.kind == lineUndecided {
+		r.kind = linePlain
+	}
+	return r.emitProse(text)
+}
+
+// emitProse writes prose text in the style of the current line.
+func (r *MarkdownRenderer) emitProse(text string) error {
+	var out, run strings.Builder
+	var runColor *color.Color
+	flush := func() {
+		if runColor != nil {
+			out.WriteString(runColor.Sprint(run.String()))
+		} else {
+			out.WriteString(run.String())
+		}
+		run.Reset()
+	}
+
+	for i, ch := range text {
+		var c *color.Color
+		switch {
+		case r.kind == lineHeading:
+			c = headingColor
+		case r.kind == lineQuote:
+			c = quoteColor
+		case r.kind == lineBullet && i == len(text)-len(strings.TrimLeft(text, " ")) && strings.ContainsRune("-*+", ch):
+			// Color the list marker only; later chunks of the line are plain prose.
+			c = bulletColor
+			r.kind = linePlain
+		case ch == '`':
+			r.inInline = !r.inInline
+			c = inlineColor
+		case r.inInline:
+			c = inlineColor
+		}
+		if c != runColor {
+			flush()
+			runColor = c
+		}
+		run.WriteRune(ch)
+	}
+	flush()
+
+	_, err := io.WriteString(r.w, out.String())
+	return err
+}
+
+func (r *MarkdownRenderer) reset() {
+	r.kind = lineUndecided
+	r.inInline = false
+}
+
+// classifyLine decides the style of a prose line from its first characters,
+// returning lineUndecided while more input is needed.
+func classifyLine(prefix string) lineKind {
+	trimmed := strings.TrimLeft(prefix, " ")
+	if trimmed == "" {
+		if len(prefix) >= 4 {
+			return linePlain
+		}
+		return lineUndecided
+	}
+
+	switch trimmed[0] {
+	case '`':
+		if strings.HasPrefix(trimmed, "```") {
+			return lineFence
+		}
+		if strings.Trim(trimmed, "`") == "" {
+			return lineUndecided
+		}
+		return linePlain
+	case '#':
+		rest := strings.TrimLeft(trimmed, "#")
+		if rest == "" {
+			return lineUndecided
+		}
+		if rest[0] == ' ' {
+			return lineHeading
+		}
+		return linePlain
+	case '-', '*', '+':
+		if len(trimmed) < 2 {
+			return lineUndecided
+		}
+		if trimmed[1] == ' ' {
+			return lineBullet
+		}
+		return linePlain
+	case '>':
+		return lineQuote
+	}
+	return linePlain
+}
+
+// highlightCode applies simple keyword, string and comment highlighting to a line of code.
+func highlightCode(line string) string {
+	var out strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			out.WriteString(commentColor.Sprint(string(runes[i:])))
+			return out.String()
+		case ch == '"' || ch == '\'' || ch == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != ch {
+				if runes[end] == '\\' && ch != '`' {
+					end++
+				}
+				end++
+			}
+			if end < len(runes) {
+				end++
+			} else {
+				end = len(runes)
+			}
+			out.WriteString(stringColor.Sprint(string(runes[i:end])))
+			i = end
+		case unicode.IsLetter(ch) || ch == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			if goKeywords[word] {
+				out.WriteString(keywordColor.Sprint(word))
+			} else {
+				out.WriteString(word)
+			}
+			i = end
+		default:
+			out.WriteRune(ch)
+			i++
+		}
+	}
+	return out.String()
+}
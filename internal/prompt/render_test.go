@@ -0,0 +1,165 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// styledRune is a rendered character with the ANSI attributes in effect, e.g. "36;1".
+type styledRune struct {
+	ch    rune
+	style string
+}
+
+// decode splits terminal output into characters and their styles.
+func decode(out string) []styledRune {
+	var result []styledRune
+	style := ""
+	for i := 0; i < len(out); {
+		if strings.HasPrefix(out[i:], "\x1b[") {
+			end := strings.IndexByte(out[i:], 'm')
+			style = out[i+2 : i+end]
+			// fatih/color resets bold and faint text with "0;22".
+			if style == "0" || strings.HasPrefix(style, "0;") {
+				style = ""
+			}
+			i += end + 1
+			continue
+		}
+		r := []rune(out[i:])[0]
+		result = append(result, styledRune{r, style})
+		i += len(string(r))
+	}
+	return result
+}
+
+// render feeds text to a MarkdownRenderer in chunks of size bytes.
+func render(t *testing.T, text string, size int) []styledRune {
+	t.Helper()
+	var out strings.Builder
+	r := NewMarkdownRenderer(&out)
+	data := []byte(text)
+	for len(data) > 0 {
+		n := min(size, len(data))
+		if _, err := r.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return decode(out.String())
+}
+
+// styleOf returns the style of the first occurrence of part in the rendered text, or "mixed".
+func styleOf(rendered []styledRune, part string) string {
+	var text strings.Builder
+	for _, s := range rendered {
+		text.WriteRune(s.ch)
+	}
+	at := strings.Index(text.String(), part)
+	if at < 0 {
+		return "missing"
+	}
+	start := len([]rune(text.String()[:at]))
+	style := rendered[start].style
+	for _, s := range rendered[start : start+len([]rune(part))] {
+		if s.style != style {
+			return "mixed"
+		}
+	}
+	return style
+}
+
+const markdown = "# Title with `code`\n" +
+	"Some `inline` text, café ☕\n" +
+	"- item one\n" +
+	"> quoted\n" +
+	"```go\n" +
+	"func main() { // entry\n" +
+	"\ts := \"x\"\n" +
+	"```\n" +
+	"#hashtag\n" +
+	"tail without newline"
+
+func TestMarkdownRendererChunks(t *testing.T) {
+	saved := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = saved })
+
+	whole := render(t, markdown, len(markdown))
+	var text strings.Builder
+	for _, s := range whole {
+		text.WriteRune(s.ch)
+	}
+	if text.String() != markdown {
+		t.Fatalf("rendered text =\n%s\nwant\n%s", text.String(), markdown)
+	}
+
+	for part, want := range map[string]string{
+		"# Title with `code`":  "36;1",
+		"Some ":                "",
+		"`inline`":             "33",
+		"café ☕":               "",
+		"-":                    "33",
+		" item one":            "",
+		"> quoted":             "2;3",
+		"```go":                "2",
+		"func":                 "35",
+		" main() { ":           "",
+		"// entry":             "90",
+		"\"x\"":                "32",
+		"#hashtag":             "",
+		"tail without newline": "",
+	} {
+		if got := styleOf(whole, part); got != want {
+			t.Errorf("style of %q = %q, want %q", part, got, want)
+		}
+	}
+
+	// Splitting the stream anywhere, even inside a fence marker, a heading's "# " or a
+	// multi-byte character, renders the same characters in the same styles.
+	for size := 1; size < 12; size++ {
+		chunked := render(t, markdown, size)
+		if len(chunked) != len(whole) {
+			t.Errorf("chunks of %d: rendered %d characters, want %d", size, len(chunked), len(whole))
+			continue
+		}
+		for i := range whole {
+			if chunked[i] != whole[i] {
+				t.Errorf("chunks of %d: character %d is %q in style %q, want %q in style %q",
+					size, i, chunked[i].ch, chunked[i].style, whole[i].ch, whole[i].style)
+				break
+			}
+		}
+	}
+}
+
+func TestMarkdownRendererUndecidedLineAtEnd(t *testing.T) {
+	saved := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = saved })
+
+	// Lines still undecided when the stream ends are written by Flush.
+	for _, text := range []string{"#", "-", "``", "  "} {
+		var out strings.Builder
+		r := NewMarkdownRenderer(&out)
+		r.Write([]byte(text))
+		if out.Len() != 0 {
+			t.Errorf("%q was written before the line was complete: %q", text, out.String())
+		}
+		if err := r.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		var got strings.Builder
+		for _, s := range decode(out.String()) {
+			got.WriteRune(s.ch)
+		}
+		if got.String() != text {
+			t.Errorf("flushed %q, want %q", got.String(), text)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package prompt
+
+import (
+	"agent/gorani/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Errors reported by the prompt functions, matched with errors.Is.
+var (
+	ErrTimeout      = errors.New("request timed out")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNoChoices    = errors.New("OpenAI returned no choices")
+)
+
+// maxRetryAfter is the longest server-requested wait gorani honors before giving up.
+const maxRetryAfter = 5 * time.Minute
+
+// RequestError describes an LLM request that failed after all attempts.
+type RequestError struct {
+	// Attempts is the number of times the request was sent.
+	Attempts int
+	// StatusCode is the HTTP status of the last attempt, or 0 when no response was received.
+	StatusCode int
+	// RetryAfter is the delay the server asked for on the last attempt, if any.
+	RetryAfter time.Duration
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	attempts := "1 attempt"
+	if e.Attempts != 1 {
+		attempts = fmt.Sprintf("%d attempts", e.Attempts)
+	}
+	return fmt.Sprintf("OpenAI request failed after %s: %v", attempts, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Is maps the HTTP status of the failure to the sentinel errors.
+func (e *RequestError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// Temporary reports whether trying again later may succeed.
+func (e *RequestError) Temporary() bool {
+	_, _, retryable := classify(e.Err)
+	return retryable
+}
+
+// RetryPolicy controls timeouts and retries of LLM requests.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first one.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles on every retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff.
+	MaxDelay time.Duration
+	// Timeout bounds each attempt.
+	Timeout time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy configured in settings.toml.
+func DefaultRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Timeout:     3 * time.Minute,
+	}
+	settings, err := config.Load()
+	if err != nil {
+		return policy
+	}
+	if settings.OpenAI.MaxRetries > 0 {
+		policy.MaxAttempts = settings.OpenAI.MaxRetries + 1
+	}
+	if settings.OpenAI.Timeout > 0 {
+		policy.Timeout = settings.OpenAI.Timeout
+	}
+	return policy
+}
+
+// noRetry marks an error that must not be retried, such as a stream failing after output was shown.
+type noRetry struct {
+	error
+}
+
+func (e noRetry) Unwrap() error {
+	return e.error
+}
+
+// do runs call with a per-attempt timeout, retrying rate limits, server errors, timeouts and
+// network failures with exponential backoff. Cancelling ctx stops immediately and returns ctx.Err().
+func do[T any](ctx context.Context, policy RetryPolicy, call func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, policy.Timeout)
+		result, err := call(attemptCtx)
+		timedOut := attemptCtx.Err() == context.DeadlineExceeded
+		cancel()
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		if timedOut {
+			err = fmt.Errorf("%w after %s", ErrTimeout, policy.Timeout)
+		}
+
+		status, retryAfter, retryable := classify(err)
+		var stop noRetry
+		if errors.As(err, &stop) {
+			retryable = false
+		}
+		if !retryable || attempt >= policy.MaxAttempts || retryAfter > maxRetryAfter {
+			return zero, &RequestError{Attempts: attempt, StatusCode: status, RetryAfter: retryAfter, Err: err}
+		}
+
+		delay := backoff(policy, attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		fmt.Printf("⏳ OpenAI request failed (%v), retrying in %s...\n", err, delay.Round(100*time.Millisecond))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// backoff returns the exponential delay before the given retry, with up to 20% jitter.
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// classify extracts the HTTP status and Retry-After delay of an error and decides whether it is worth retrying.
+func classify(err error) (status int, retryAfter time.Duration, retryable bool) {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+		if apiErr.Response != nil {
+			retryAfter = parseRetryAfter(apiErr.Response.Header)
+		}
+		retryable = status == http.StatusRequestTimeout || status == http.StatusConflict ||
+			status == http.StatusTooManyRequests || status >= 500
+		return status, retryAfter, retryable
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrTimeout):
+		retryable = true
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF):
+		retryable = true
+	}
+	return 0, 0, retryable
+}
+
+// parseRetryAfter reads the Retry-After-Ms or Retry-After (seconds or HTTP date) header.
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package prompt
+
+import (
+	"context"
+	"io"
+)
+
+// StreamOpenai sends the given input to OpenAI as a free-form request and renders the answer
+// to w as Markdown while it streams. It returns the complete answer once the stream ends.
+// Cancelling ctx aborts the request; the content received so far is returned with the error.
+func StreamOpenai(ctx context.Context, input string, w io.Writer) (string, error) {
+	content, _, err := StreamChat(ctx, DefaultModel(), []Message{UserMessage(input)}, w)
+	return content, err
+}
+
+// StreamChat sends a conversation to the given model and renders the answer to w as it streams.
+// It returns the complete answer and its token usage once the stream ends. Failures before
+// anything was rendered are retried; a stream breaking off midway is not.
+func StreamChat(ctx context.Context, model string, messages []Message, w io.Writer) (string, Usage, error) {
+	renderer := NewMarkdownRenderer(w)
+	resp, err := DefaultProvider().Complete(ctx, &Request{
+		Model:    model,
+		Messages: messages,
+		OnDelta: func(delta string) error {
+			_, err := io.WriteString(renderer, delta)
+			return err
+		},
+	})
+	if flushErr := renderer.Flush(); err == nil {
+		err = flushErr
+	}
+	if resp == nil {
+		return "", Usage{}, err
+	}
+	return resp.Message.Content, resp.Usage, err
+}
@@ -0,0 +1,105 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Result is the decoded answer to a structured request.
+type Result[T any] struct {
+	// Value is the answer decoded into T.
+	Value T
+	// Raw is the JSON text returned by the model.
+	Raw string
+	// Usage is the token usage of the request.
+	Usage Usage
+	// Model is the model that answered.
+	Model string
+}
+
+// ErrInvalidResponse is returned when the model's answer does not match the requested schema.
+var ErrInvalidResponse = errors.New("invalid structured response")
+
+// Ask sends a conversation to the provider, requiring a strict JSON answer matching the schema
+// of T, and returns the decoded value. An answer that fails validation is sent back to the
+// model with the error once before giving up.
+func Ask[T any](ctx context.Context, provider Provider, messages []Message) (T, error) {
+	result, err := ask[T](ctx, provider, "", messages)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result.Value, nil
+}
+
+// Structured asks the given model for a structured answer like Ask and returns it
+// together with the raw JSON and token usage.
+func Structured[T any](ctx context.Context, model string, messages []Message) (*Result[T], error) {
+	return ask[T](ctx, DefaultProvider(), model, messages)
+}
+
+// ask implements Ask and Structured; an empty model uses the provider's default.
+func ask[T any](ctx context.Context, provider Provider, model string, messages []Message) (*Result[T], error) {
+	name := schemaName[T]()
+	schema := &Schema{
+		Name:        name,
+		Description: "Response in the " + name + " format",
+		Definition:  GenerateSchema[T](),
+	}
+
+	result := &Result[T]{}
+	conversation := append([]Message(nil), messages...)
+	for attempt := 1; ; attempt++ {
+		resp, err := provider.Complete(ctx, &Request{Model: model, Messages: conversation, Schema: schema})
+		if err != nil {
+			return nil, err
+		}
+		result.Raw = resp.Message.Content
+		result.Model = resp.Model
+		result.Usage.Add(resp.Usage)
+
+		err = validateJSON(schema.Definition, []byte(result.Raw))
+		if err == nil {
+			err = json.Unmarshal([]byte(result.Raw), &result.Value)
+		}
+		if err == nil {
+			return result, nil
+		}
+		if attempt > 1 {
+			return result, fmt.Errorf("%w: %s answer: %v", ErrInvalidResponse, name, err)
+		}
+
+		// Give the model one chance to correct itself.
+		conversation = append(conversation,
+			AssistantMessage(result.Raw),
+			UserMessage(fmt.Sprintf("Your answer does not match the %s JSON schema: %v\n"+
+				"Reply again with only a JSON document that matches the schema.", name, err)),
+		)
+	}
+}
+
+// schemaName derives a schema name such as "code_response" from the name of T.
+func schemaName[T any]() string {
+	var v T
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Name() == "" {
+		return "response"
+	}
+
+	var sb strings.Builder
+	for i, ch := range typ.Name() {
+		if unicode.IsUpper(ch) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			ch = unicode.ToLower(ch)
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
@@ -0,0 +1,60 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// verdict is the structured answer asked for in the tests.
+type verdict struct {
+	Approve bool   `json:"approve"`
+	Reason  string `json:"reason"`
+}
+
+// sequenceProvider answers requests with the given answers, in order.
+type sequenceProvider struct {
+	answers []string
+}
+
+func (p *sequenceProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return &Response{Message: AssistantMessage(answer), Model: "test-model", Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}, nil
+}
+
+// recordAnswers records the answers to the requests Ask makes, then returns a provider
+// replaying them.
+func recordAnswers(t *testing.T, answers ...string) Provider {
+	t.Helper()
+	dir := t.TempDir()
+	recorder := NewRecorder(&sequenceProvider{answers}, dir, "openai", "test-model")
+	// Recording runs the same conversation, so the replayer finds a fixture for every request.
+	Ask[verdict](context.Background(), recorder, []Message{UserMessage("Review the change.")})
+	return NewReplayer(dir, "openai", "test-model")
+}
+
+func TestAskRetriesInvalidAnswer(t *testing.T) {
+	provider := recordAnswers(t, `{"approve": "yes"}`, `{"approve": true, "reason": "small and tested"}`)
+	got, err := Ask[verdict](context.Background(), provider, []Message{UserMessage("Review the change.")})
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if want := (verdict{Approve: true, Reason: "small and tested"}); got != want {
+		t.Errorf("Ask = %+v, want %+v", got, want)
+	}
+}
+
+func TestAskGivesUpAfterSecondInvalidAnswer(t *testing.T) {
+	provider := recordAnswers(t, `{"approve": "yes"}`, `{"approve": true}`)
+	result, err := ask[verdict](context.Background(), provider, "", []Message{UserMessage("Review the change.")})
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("ask error = %v, want ErrInvalidResponse", err)
+	}
+	if result.Raw != `{"approve": true}` {
+		t.Errorf("Raw = %q, want the second answer", result.Raw)
+	}
+	if result.Usage.TotalTokens != 30 {
+		t.Errorf("Usage = %+v, want both requests counted", result.Usage)
+	}
+}
@@ -0,0 +1,54 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openai/openai-go/shared"
+)
+
+// ToolSpec describes a function the model may call.
+type ToolSpec struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments, usually from GenerateSchema.
+	Parameters interface{}
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// CompleteWithTools sends a conversation to the given model along with the tools it may call.
+// The returned assistant message either answers or lists the tool calls the model wants made.
+func CompleteWithTools(ctx context.Context, model string, messages []Message, tools []ToolSpec) (Message, Usage, error) {
+	resp, err := DefaultProvider().Complete(ctx, &Request{Model: model, Messages: messages, Tools: tools})
+	if err != nil {
+		return Message{}, Usage{}, err
+	}
+	return resp.Message, resp.Usage, nil
+}
+
+// schemaToMap converts a schema value to the generic map the tools API expects.
+func schemaToMap(schema interface{}) (shared.FunctionParameters, error) {
+	if schema == nil {
+		return shared.FunctionParameters{
+			"type":                 "object",
+			"properties":           map[string]interface{}{},
+			"required":             []string{},
+			"additionalProperties": false,
+		}, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var parameters shared.FunctionParameters
+	if err := json.Unmarshal(data, &parameters); err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
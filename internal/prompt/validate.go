@@ -0,0 +1,198 @@
+package prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// validateJSON checks a JSON document against a schema from GenerateSchema.
+// It covers the subset of JSON schema used by strict structured outputs: type, properties,
+// required, additionalProperties, items, enum, const and anyOf.
+func validateJSON(schema interface{}, data []byte) error {
+	rules, err := schemaToMap(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("not valid JSON: %v", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("not valid JSON: unexpected data after the top-level value")
+	}
+	return validateValue(rules, value, "$")
+}
+
+// validateValue checks value against a single schema node; path locates it in error messages.
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), typeOf(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+	if constant, ok := schema["const"]; ok && !sameValue(constant, value) {
+		return fmt.Errorf("%s: expected %v, got %v", path, constant, value)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, option := range anyOf {
+			rules, _ := option.(map[string]interface{})
+			err := validateValue(rules, value, path)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%s: matches none of the allowed schemas (%s)", path, strings.Join(errs, "; "))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, v, path)
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range v {
+			if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateObject checks the required, known and additional properties of an object.
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, ok := object[key]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, key)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childPath := path + "." + key
+		if rules, ok := properties[key].(map[string]interface{}); ok {
+			if err := validateValue(rules, object[key], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, key)
+			}
+		case map[string]interface{}:
+			if err := validateValue(additional, object[key], childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypes returns the allowed types of a "type" keyword, which may be a string or a list.
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value is of the given JSON schema type.
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return typeOf(value) == t
+	}
+}
+
+// typeOf names the JSON type of a decoded value.
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// containsValue reports whether value equals one of the options.
+func containsValue(options []interface{}, value interface{}) bool {
+	for _, option := range options {
+		if sameValue(option, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue compares a schema constant with a decoded value, treating numbers by their text.
+func sameValue(constant, value interface{}) bool {
+	if n, ok := value.(json.Number); ok {
+		return fmt.Sprint(constant) == n.String()
+	}
+	return reflect.DeepEqual(constant, value)
+}
@@ -0,0 +1,60 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+// reviewSchema is a schema in the form GenerateSchema produces for strict structured outputs.
+var reviewSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"verdict": map[string]interface{}{"type": "string", "enum": []string{"approve", "reject"}},
+		"score":   map[string]interface{}{"type": "integer"},
+		"notes": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+		"reviewer": map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{"type": "null"},
+			},
+		},
+	},
+	"required":             []string{"verdict", "score", "notes", "reviewer"},
+	"additionalProperties": false,
+}
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// err is a part of the expected error, or "" when the document is valid.
+		err string
+	}{
+		{"valid", `{"verdict": "approve", "score": 3, "notes": ["ok"], "reviewer": null}`, ""},
+		{"valid with string in anyOf", `{"verdict": "reject", "score": 0, "notes": [], "reviewer": "kim"}`, ""},
+		{"missing required field", `{"verdict": "approve", "score": 3, "notes": []}`, `$: missing required property "reviewer"`},
+		{"wrong type", `{"verdict": "approve", "score": "3", "notes": [], "reviewer": null}`, "$.score: expected integer, got string"},
+		{"fraction for integer", `{"verdict": "approve", "score": 2.5, "notes": [], "reviewer": null}`, "$.score: expected integer, got number"},
+		{"wrong item type", `{"verdict": "approve", "score": 3, "notes": ["ok", 4], "reviewer": null}`, "$.notes[1]: expected string, got number"},
+		{"extra property", `{"verdict": "approve", "score": 3, "notes": [], "reviewer": null, "mood": "happy"}`, `$: unexpected property "mood"`},
+		{"value outside enum", `{"verdict": "maybe", "score": 3, "notes": [], "reviewer": null}`, "$.verdict: maybe is not one of [approve reject]"},
+		{"no anyOf option", `{"verdict": "approve", "score": 3, "notes": [], "reviewer": 7}`, "$.reviewer: matches none of the allowed schemas"},
+		{"not an object", `["approve"]`, "$: expected object, got array"},
+		{"not JSON", `{"verdict": `, "not valid JSON"},
+		{"trailing data", `{"verdict": "approve", "score": 3, "notes": [], "reviewer": null} {}`, "unexpected data after the top-level value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJSON(reviewSchema, []byte(tt.data))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,4 +1,323 @@
 package repl
 
-func grabFunctions() {
+import (
+	"agent/gorani/internal/grab"
+	"agent/gorani/internal/journal"
+	"agent/gorani/internal/prompt"
+	"agent/gorani/internal/session"
+	"agent/gorani/internal/tree"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// Colored formatters for REPL output.
+var (
+	promptColor = color.New(color.FgCyan, color.Bold) // Input prompt in cyan.
+	infoColor   = color.New(color.FgHiBlack)          // Status messages dimmed.
+	errorColor  = color.New(color.FgRed)              // Errors in red.
+)
+
+// Options configures a chat REPL.
+type Options struct {
+	// Model is the model to talk to. Defaults to the session's model, then settings.toml.
+	Model string
+	// Session is the conversation to resume. A new session is started when nil.
+	Session *session.Session
+}
+
+// command is a slash command available in the REPL.
+type command struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, c *chat, args []string) error
+}
+
+// commands maps slash command names to their implementation.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"grab":    {"/grab <path>...", "attach files or folders to the conversation", grabCommand},
+		"tree":    {"/tree [path]", "attach the directory tree", treeCommand},
+		"summary": {"/summary [path]", "attach a summary of Go symbols", summaryCommand},
+		"apply":   {"/apply", "write the file from the last answer to disk", applyCommand},
+		"model":   {"/model [name]", "show or switch the model", modelCommand},
+		"clear":   {"/clear", "forget the conversation history", clearCommand},
+		"save":    {"/save [file]", "save the transcript as Markdown (default chat.md)", saveCommand},
+		"help":    {"/help", "list commands", helpCommand},
+	}
+}
+
+// chat holds the state of a REPL session.
+type chat struct {
+	model   string
+	history []prompt.Message
+	session *session.Session
+	out     io.Writer
+}
+
+// Run starts an interactive chat with the model on stdin/stdout.
+// It returns when the user types /exit or sends EOF (Ctrl-D or Ctrl-C at the prompt).
+func Run(ctx context.Context, opts Options) error {
+	c := &chat{model: opts.Model, session: opts.Session, out: os.Stdout}
+	if c.session == nil {
+		if c.model == "" {
+			c.model = prompt.DefaultModel()
+		}
+		s, err := session.New(c.model)
+		if err != nil {
+			return err
+		}
+		c.session = s
+	} else {
+		c.history = append(c.history, c.session.Messages...)
+		if c.model == "" {
+			c.model = c.session.Model
+		}
+		if c.model == "" {
+			c.model = prompt.DefaultModel()
+		}
+		if c.model != c.session.Model {
+			c.record(c.session.SetModel(c.model))
+		}
+		infoColor.Fprintf(c.out, "Resumed session %s with %d messages.\n", c.session.ID, len(c.history))
+	}
+
+	readLine := newLineReader()
+
+	infoColor.Fprintf(c.out, "Chatting with %s in session %s. Type /help for commands, /exit to quit.\n", c.model, c.session.ID)
+	for {
+		line, err := readLine(promptColor.Sprint("gorani> "))
+		if err == io.EOF {
+			fmt.Fprintln(c.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "/exit" || line == "/quit" {
+			return nil
+		}
+
+		if strings.HasPrefix(line, "/") {
+			if err := c.runCommand(ctx, line); err != nil {
+				errorColor.Fprintln(c.out, "Error:", err)
+			}
+			continue
+		}
+
+		if err := c.send(ctx, line); err != nil {
+			errorColor.Fprintln(c.out, "Error:", err)
+		}
+	}
+}
+
+// send adds the user's message to the history and streams the model's answer.
+// Ctrl-C while streaming cancels the answer without leaving the REPL.
+func (c *chat) send(ctx context.Context, text string) error {
+	reqCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	messages := append(c.history, prompt.UserMessage(text))
+	answer, usage, err := prompt.StreamChat(reqCtx, c.model, messages, c.out)
+	fmt.Fprintln(c.out)
+	if err != nil {
+		if reqCtx.Err() != nil {
+			return fmt.Errorf("request cancelled")
+		}
+		return err
+	}
+
+	c.history = append(messages, prompt.AssistantMessage(answer))
+	c.record(c.session.AddMessage(prompt.UserMessage(text)))
+	c.record(c.session.AddMessage(prompt.AssistantMessage(answer)))
+	c.record(c.session.AddUsage(c.model, usage))
+	return nil
+}
+
+// record reports a failure to persist the session without interrupting the chat.
+func (c *chat) record(err error) {
+	if err != nil {
+		errorColor.Fprintln(c.out, "Warning: session not saved:", err)
+	}
+}
+
+// runCommand dispatches a slash command line.
+func (c *chat) runCommand(ctx context.Context, line string) error {
+	fields := strings.Fields(strings.TrimPrefix(line, "/"))
+	if len(fields) == 0 {
+		return helpCommand(ctx, c, nil)
+	}
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return fmt.Errorf("unknown command /%s (try /help)", fields[0])
+	}
+	return cmd.run(ctx, c, fields[1:])
+}
+
+// attach adds context to the conversation as a user message without asking the model yet.
+// files lists the paths the content was read from, if any.
+func (c *chat) attach(what, content string, files []string) {
+	message := prompt.UserMessage("Here is the " + what + ":\n\n" + content)
+	c.history = append(c.history, message)
+	c.record(c.session.AddAttachment(message, files))
+	infoColor.Fprintf(c.out, "Attached %s (%d bytes).\n", what, len(content))
+}
+
+func grabCommand(_ context.Context, c *chat, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /grab <path>...")
+	}
+	content, err := grab.CollectContent(args)
+	if err != nil {
+		return err
+	}
+	c.attach("content of "+strings.Join(args, ", "), content, args)
+	return nil
+}
+
+func treeCommand(_ context.Context, c *chat, args []string) error {
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+	treeStr, err := tree.GenerateTreeString(root, "")
+	if err != nil {
+		return err
+	}
+	c.attach("directory tree of "+root, treeStr, nil)
+	return nil
+}
+
+func summaryCommand(_ context.Context, c *chat, args []string) error {
+	root := "./"
+	if len(args) > 0 {
+		root = args[0]
+	}
+	summary, err := grab.BuildSummary(root)
+	if err != nil {
+		return err
+	}
+	c.attach("summary of the code in "+root, summary, nil)
+	return nil
+}
+
+// applyCommand asks the model to restate its last answer as a CodeResponse and writes it to disk.
+func applyCommand(ctx context.Context, c *chat, _ []string) error {
+	if len(c.history) == 0 || c.history[len(c.history)-1].Role != prompt.RoleAssistant {
+		return fmt.Errorf("nothing to apply: ask the model for code first")
+	}
+
+	reqCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	messages := append(c.history, prompt.UserMessage(
+		"Return the complete content of the file from your last answer, with its path as the filename."))
+	result, err := prompt.Structured[prompt.CodeResponse](reqCtx, c.model, messages)
+	if err != nil {
+		return err
+	}
+	c.record(c.session.AddUsage(c.model, result.Usage))
+	return prompt.ApplyCodeResponse(result.Value)
+}
+
+func modelCommand(_ context.Context, c *chat, args []string) error {
+	if len(args) == 0 {
+		infoColor.Fprintf(c.out, "Current model: %s\n", c.model)
+		return nil
+	}
+	c.model = args[0]
+	c.record(c.session.SetModel(c.model))
+	infoColor.Fprintf(c.out, "Switched to %s.\n", c.model)
+	return nil
+}
+
+func clearCommand(_ context.Context, c *chat, _ []string) error {
+	c.history = nil
+	c.record(c.session.Clear())
+	infoColor.Fprintln(c.out, "Conversation cleared.")
+	return nil
+}
+
+func saveCommand(_ context.Context, c *chat, args []string) error {
+	path := "chat.md"
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	var sb strings.Builder
+	for _, m := range c.history {
+		fmt.Fprintf(&sb, "## %s\n\n%s\n\n", m.Role, strings.TrimSpace(m.Content))
+	}
+	if err := journal.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to save transcript to %s: %v", path, err)
+	}
+	infoColor.Fprintf(c.out, "Transcript saved to %s.\n", path)
+	return nil
+}
+
+func helpCommand(_ context.Context, c *chat, _ []string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(c.out, "  %-18s %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintf(c.out, "  %-18s %s\n", "/exit", "leave the chat")
+	return nil
+}
+
+// newLineReader returns a function reading one line of input. On a terminal it provides
+// line editing and in-session history; otherwise it reads plain lines from stdin.
+func newLineReader() func(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		return func(label string) (string, error) {
+			fmt.Print(label)
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}
+	}
+
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	return func(label string) (string, error) {
+		// Raw mode is only enabled while reading so streamed answers render normally.
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(fd, state)
+
+		if width, height, err := term.GetSize(fd); err == nil {
+			terminal.SetSize(width, height)
+		}
+		terminal.SetPrompt(label)
+		return terminal.ReadLine()
+	}
 }
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// killGroup leaves the default of killing only the command itself.
+func killGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,311 @@
+package runner
+
+import (
+	"agent/gorani/internal/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// waitDelay bounds how long output is still read after a command was killed.
+const waitDelay = 5 * time.Second
+
+// Runner runs allowlisted project commands for the model: without a shell, in a pinned
+// directory, with a scrubbed environment, a timeout and capped output.
+type Runner struct {
+	// Dir is the directory commands run in, the root of the repository or worktree.
+	Dir string
+	// Allow lists the permitted commands, each a program optionally followed by leading arguments.
+	Allow []string
+	// Timeout bounds every command.
+	Timeout time.Duration
+	// MaxOutput caps the bytes kept of stdout and of stderr; the middle is dropped.
+	MaxOutput int
+	// Env names environment variables passed to commands besides the safe defaults.
+	Env []string
+}
+
+// New returns a runner configured by the [runner] section of settings.toml, pinned to the root
+// of the git repository or worktree, or to the current directory outside git.
+func New() (*Runner, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the working directory: %v", err)
+	}
+	if out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		dir = strings.TrimSpace(string(out))
+	}
+	return &Runner{
+		Dir:       dir,
+		Allow:     settings.Runner.Allow,
+		Timeout:   settings.Runner.Timeout,
+		MaxOutput: settings.Runner.MaxOutputKB * 1024,
+		Env:       settings.Runner.Env,
+	}, nil
+}
+
+// Result is the outcome of a command, in a form that can be fed back to the model.
+type Result struct {
+	Command []string `json:"command"`
+	// ExitCode is -1 when the command was killed.
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	TimedOut bool          `json:"timed_out,omitempty"`
+	Stdout   string        `json:"stdout"`
+	Stderr   string        `json:"stderr"`
+	// StdoutDropped and StderrDropped count the bytes cut from the middle of the output.
+	StdoutDropped int `json:"stdout_dropped,omitempty"`
+	StderrDropped int `json:"stderr_dropped,omitempty"`
+}
+
+// OK reports whether the command finished in time and exited with 0.
+func (r *Result) OK() bool {
+	return r.ExitCode == 0 && !r.TimedOut
+}
+
+// String renders the result for a prompt.
+func (r *Result) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "$ %s\n", commandLine(r.Command))
+	switch {
+	case r.TimedOut:
+		fmt.Fprintf(&sb, "timed out after %s\n", r.Duration.Round(time.Millisecond))
+	default:
+		fmt.Fprintf(&sb, "exit code %d after %s\n", r.ExitCode, r.Duration.Round(time.Millisecond))
+	}
+	for _, stream := range []struct {
+		name, text string
+	}{{"stdout", r.Stdout}, {"stderr", r.Stderr}} {
+		if strings.TrimSpace(stream.text) == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s:\n%s", stream.name, stream.text)
+		if !strings.HasSuffix(stream.text, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// commandLine joins args for display, quoting the ones a shell would split.
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$|&;<>()*?") {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Allowed reports whether a command is on the allowlist.
+func (r *Runner) Allowed(args []string) bool {
+	for _, entry := range r.Allow {
+		prefix := strings.Fields(entry)
+		if len(prefix) == 0 || len(args) < len(prefix) {
+			continue
+		}
+		match := true
+		for i := range prefix {
+			if args[i] != prefix[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs a command and captures its result. A command that fails or times out still returns
+// a result; the error is for commands that are not allowed or could not be started.
+func (r *Runner) Run(ctx context.Context, args ...string) (*Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+	if !r.Allowed(args) {
+		return nil, fmt.Errorf("%q is not allowed; allowed commands: %s (see [runner] allow in %s)", strings.Join(args, " "), strings.Join(r.Allow, ", "), config.SettingsFile)
+	}
+	if err := r.checkArgs(args[1:]); err != nil {
+		return nil, err
+	}
+
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	stdout, stderr := &capped{max: r.MaxOutput}, &capped{max: r.MaxOutput}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = r.Dir
+	cmd.Env = scrubEnv(os.Environ(), r.Env)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.WaitDelay = waitDelay
+	killGroup(cmd)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %v", args[0], err)
+	}
+	err := cmd.Wait()
+	result := &Result{
+		Command:       args,
+		Duration:      time.Since(start),
+		TimedOut:      errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Stdout:        stdout.String(),
+		Stderr:        stderr.String(),
+		StdoutDropped: stdout.dropped,
+		StderrDropped: stderr.dropped,
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.ExitCode = -1
+	}
+	if ctx.Err() != nil && !result.TimedOut {
+		// Interrupted by the user rather than the timeout.
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// deniedFlags change the directory a tool works in or make it run another program, which
+// would get around the allowlist: go test -exec=sh, go build -toolexec, go vet -vettool.
+var deniedFlags = map[string]bool{
+	"C":        true,
+	"exec":     true,
+	"toolexec": true,
+	"vettool":  true,
+	"overlay":  true,
+	"modfile":  true,
+}
+
+// checkArgs refuses the denied flags, in both the -flag=value and -flag value forms, and
+// arguments naming paths outside the runner's directory.
+func (r *Runner) checkArgs(args []string) error {
+	for _, arg := range args {
+		value := arg
+		if strings.HasPrefix(arg, "-") {
+			name, v, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if deniedFlags[name] {
+				return fmt.Errorf("%s: the -%s flag is not allowed", arg, name)
+			}
+			if !ok {
+				continue
+			}
+			value = v
+		}
+		path := value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.Dir, path)
+		}
+		rel, err := filepath.Rel(r.Dir, filepath.Clean(path))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s: paths outside %s are not allowed", arg, r.Dir)
+		}
+	}
+	return nil
+}
+
+// safeEnv are the environment variables commands get by default, by name or by prefix when
+// ending in "*". Everything else, API keys included, is removed.
+var safeEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "LANG", "LC_*", "TZ", "TMPDIR", "XDG_CACHE_HOME",
+	"GO*", "CGO_*", "CC", "CXX", "PKG_CONFIG_PATH",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+}
+
+// secretWords mark variables that are removed even when a safe prefix matches them,
+// unless they are passed explicitly.
+var secretWords = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "CREDENTIAL"}
+
+// scrubEnv keeps the safe variables of environ and the ones named in extra.
+func scrubEnv(environ, extra []string) []string {
+	var env []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if matchEnv(name, extra) || (matchEnv(name, safeEnv) && !secret(name)) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+func matchEnv(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func secret(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range secretWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// capped keeps the first and last max/2 bytes written to it, where build errors and test
+// failures usually are, and counts the bytes dropped in between.
+type capped struct {
+	max     int
+	head    []byte
+	tail    []byte
+	dropped int
+}
+
+func (c *capped) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.max <= 0 {
+		c.head = append(c.head, p...)
+		return n, nil
+	}
+	if room := c.max/2 - len(c.head); room > 0 {
+		take := min(room, len(p))
+		c.head = append(c.head, p[:take]...)
+		p = p[take:]
+	}
+	c.tail = append(c.tail, p...)
+	if over := len(c.tail) - (c.max - c.max/2); over > 0 {
+		c.tail = append(c.tail[:0], c.tail[over:]...)
+		c.dropped += over
+	}
+	return n, nil
+}
+
+func (c *capped) String() string {
+	if c.dropped == 0 {
+		return string(c.head) + string(c.tail)
+	}
+	var b bytes.Buffer
+	b.Write(c.head)
+	fmt.Fprintf(&b, "\n... (%d bytes of output omitted) ...\n", c.dropped)
+	b.Write(c.tail)
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckArgsDeniesFlags(t *testing.T) {
+	r := &Runner{Dir: t.TempDir(), Allow: []string{"go test", "go build", "go vet"}}
+	for _, args := range [][]string{
+		{"go", "test", "-exec=touch", "."},
+		{"go", "test", "-exec", "./x.sh", "."},
+		{"go", "test", "--exec=touch", "."},
+		{"go", "build", "-toolexec=touch", "./..."},
+		{"go", "build", "-toolexec", "touch", "./..."},
+		{"go", "vet", "-vettool=./tool", "./..."},
+		{"go", "vet", "-vettool", "./tool", "./..."},
+		{"go", "build", "-overlay=o.json", "./..."},
+		{"go", "build", "-overlay", "o.json", "./..."},
+		{"go", "build", "-modfile=alt.mod", "./..."},
+		{"go", "build", "-modfile", "alt.mod", "./..."},
+		{"go", "test", "-C", "/", "./..."},
+		{"go", "test", "-C=/", "./..."},
+	} {
+		if _, err := r.Run(context.Background(), args...); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("Run(%q) = %v, want the flag refused", args, err)
+		}
+	}
+}
+
+func TestCheckArgsPaths(t *testing.T) {
+	r := &Runner{Dir: t.TempDir()}
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"./..."}, true},
+		{[]string{"-run=TestX", "./internal/x"}, true},
+		{[]string{"-v", "-count=1", "."}, true},
+		{[]string{"../x"}, false},
+		{[]string{"/etc/passwd"}, false},
+		{[]string{"-o=../bin"}, false},
+	}
+	for _, tt := range tests {
+		if err := r.checkArgs(tt.args); (err == nil) != tt.ok {
+			t.Errorf("checkArgs(%q) = %v, want ok=%v", tt.args, err, tt.ok)
+		}
+	}
+}
+
+func TestRunAllowlist(t *testing.T) {
+	r := &Runner{Dir: t.TempDir(), Allow: []string{"go env"}}
+	if _, err := r.Run(context.Background(), "touch", "x"); err == nil {
+		t.Fatal("a command off the allowlist ran")
+	}
+	if _, err := os.Stat(filepath.Join(r.Dir, "x")); err == nil {
+		t.Fatal("a command off the allowlist created a file")
+	}
+}
+
+func TestRunScrubsEnvAndTimesOut(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("GOPRIVATE_TOKEN", "secret")
+	r := &Runner{Dir: t.TempDir(), Allow: []string{"sh -c"}, Timeout: 500 * time.Millisecond}
+
+	result, err := r.Run(context.Background(), "sh", "-c", "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(result.Stdout, "OPENAI_API_KEY") || strings.Contains(result.Stdout, "GOPRIVATE_TOKEN") {
+		t.Errorf("secrets leaked into the environment:\n%s", result.Stdout)
+	}
+
+	start := time.Now()
+	result, err = r.Run(context.Background(), "sh", "-c", "sleep 30 & sleep 30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TimedOut || time.Since(start) > 10*time.Second {
+		t.Errorf("TimedOut = %v after %s, want a timeout after 500ms", result.TimedOut, time.Since(start))
+	}
+}
+
+func TestCapped(t *testing.T) {
+	c := &capped{max: 10}
+	c.Write([]byte("0123456789abcdefghij"))
+	if got, want := c.String(), "01234\n... (10 bytes of output omitted) ...\nfghij"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package session
+
+import (
+	"agent/gorani/internal/config"
+	"agent/gorani/internal/prompt"
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry kinds stored in a session file.
+const (
+	KindMeta    = "meta"    // model selection
+	KindMessage = "message" // a conversation turn, optionally with attached files
+	KindUsage   = "usage"   // token usage of one request
+	KindClear   = "clear"   // the history was cleared
+)
+
+// Entry is a single line of a session file.
+type Entry struct {
+	Time    time.Time       `json:"time"`
+	Kind    string          `json:"kind"`
+	Model   string          `json:"model,omitempty"`
+	Message *prompt.Message `json:"message,omitempty"`
+	Files   []string        `json:"files,omitempty"`
+	Usage   *prompt.Usage   `json:"usage,omitempty"`
+}
+
+// Session is a conversation persisted under .gorani/sessions/<id>.jsonl.
+// Every change is appended to the file as it happens, so an interrupted
+// session can always be resumed.
+type Session struct {
+	ID          string           `json:"id"`
+	Model       string           `json:"model"`
+	Messages    []prompt.Message `json:"messages"`
+	Attachments []string         `json:"attachments,omitempty"`
+	Usage       prompt.Usage     `json:"usage"`
+	Created     time.Time        `json:"created"`
+	Updated     time.Time        `json:"updated"`
+}
+
+// Dir returns the directory holding session files.
+func Dir() string {
+	return config.StatePath("sessions")
+}
+
+func path(id string) string {
+	return filepath.Join(Dir(), id+".jsonl")
+}
+
+// New returns a fresh session for the given model. Nothing is written until the first message.
+func New(model string) (*Session, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %v", err)
+	}
+	now := time.Now()
+	return &Session{
+		ID:      now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		Model:   model,
+		Created: now,
+		Updated: now,
+	}, nil
+}
+
+// Open loads the session with the given id or unique id prefix.
+func Open(id string) (*Session, error) {
+	id, err := resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session %s: %v", id, err)
+	}
+	defer file.Close()
+
+	s := &Session{ID: id}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("session %s line %d: %v", id, line, err)
+		}
+		s.replay(e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %v", id, err)
+	}
+	return s, nil
+}
+
+// replay applies a stored entry to the in-memory session.
+func (s *Session) replay(e Entry) {
+	if s.Created.IsZero() {
+		s.Created = e.Time
+	}
+	s.Updated = e.Time
+
+	switch e.Kind {
+	case KindMeta:
+		s.Model = e.Model
+	case KindMessage:
+		if e.Message != nil {
+			s.Messages = append(s.Messages, *e.Message)
+		}
+		s.Attachments = append(s.Attachments, e.Files...)
+	case KindUsage:
+		if e.Usage != nil {
+			s.Usage.Add(*e.Usage)
+		}
+	case KindClear:
+		s.Messages = nil
+		s.Attachments = nil
+	}
+}
+
+// append applies the entry and writes it to the session file. A new session file starts
+// with a meta entry recording the model.
+func (s *Session) append(e Entry) error {
+	e.Time = time.Now()
+
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", Dir(), err)
+	}
+	var entries []Entry
+	if _, err := os.Stat(path(s.ID)); os.IsNotExist(err) && e.Kind != KindMeta {
+		entries = append(entries, Entry{Time: e.Time, Kind: KindMeta, Model: s.Model})
+	}
+	entries = append(entries, e)
+
+	file, err := os.OpenFile(path(s.ID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open session %s: %v", s.ID, err)
+	}
+	defer file.Close()
+
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write session %s: %v", s.ID, err)
+		}
+		s.replay(entry)
+	}
+	return nil
+}
+
+// AddMessage records a conversation turn.
+func (s *Session) AddMessage(m prompt.Message) error {
+	return s.append(Entry{Kind: KindMessage, Message: &m})
+}
+
+// AddAttachment records a message carrying the contents of the given files.
+func (s *Session) AddAttachment(m prompt.Message, files []string) error {
+	return s.append(Entry{Kind: KindMessage, Message: &m, Files: files})
+}
+
+// AddUsage records the token usage of a request.
+func (s *Session) AddUsage(model string, u prompt.Usage) error {
+	return s.append(Entry{Kind: KindUsage, Model: model, Usage: &u})
+}
+
+// SetModel records a switch to another model.
+func (s *Session) SetModel(model string) error {
+	s.Model = model
+	if _, err := os.Stat(path(s.ID)); os.IsNotExist(err) {
+		// The meta entry is written with the first message.
+		return nil
+	}
+	return s.append(Entry{Kind: KindMeta, Model: model})
+}
+
+// Clear records that the conversation history was cleared.
+func (s *Session) Clear() error {
+	return s.append(Entry{Kind: KindClear})
+}
+
+// Title returns a one-line description of the session, taken from its first user message.
+func (s *Session) Title() string {
+	for _, m := range s.Messages {
+		if m.Role != prompt.RoleUser {
+			continue
+		}
+		title := strings.Join(strings.Fields(m.Content), " ")
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+		return title
+	}
+	return "(empty)"
+}
+
+// WriteMarkdown writes the conversation as a Markdown transcript.
+func (s *Session) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "# Session %s\n\n", s.ID)
+	fmt.Fprintf(w, "- Model: %s\n- Created: %s\n- Updated: %s\n- Tokens: %d (prompt %d, completion %d)\n",
+		s.Model, s.Created.Format(time.RFC3339), s.Updated.Format(time.RFC3339),
+		s.Usage.TotalTokens, s.Usage.PromptTokens, s.Usage.CompletionTokens)
+	if len(s.Attachments) > 0 {
+		fmt.Fprintf(w, "- Attachments: %s\n", strings.Join(s.Attachments, ", "))
+	}
+	fmt.Fprintln(w)
+
+	for _, m := range s.Messages {
+		if _, err := fmt.Fprintf(w, "## %s\n\n%s\n\n", m.Role, strings.TrimSpace(m.Content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the session state as a single JSON document.
+func (s *Session) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// List returns all stored sessions, most recently updated first.
+func List() ([]*Session, error) {
+	files, err := filepath.Glob(filepath.Join(Dir(), "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, file := range files {
+		s, err := Open(strings.TrimSuffix(filepath.Base(file), ".jsonl"))
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+// Remove deletes the session with the given id or unique id prefix.
+func Remove(id string) error {
+	id, err := resolve(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path(id)); err != nil {
+		return fmt.Errorf("failed to remove session %s: %v", id, err)
+	}
+	return nil
+}
+
+// resolve expands a unique id prefix to a full session id.
+func resolve(prefix string) (string, error) {
+	if prefix == "" {
+		return "", fmt.Errorf("no session id given")
+	}
+	if _, err := os.Stat(path(prefix)); err == nil {
+		return prefix, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(Dir(), prefix+"*.jsonl"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("session %s not found", prefix)
+	case 1:
+		return strings.TrimSuffix(filepath.Base(matches[0]), ".jsonl"), nil
+	default:
+		return "", fmt.Errorf("session id %s is ambiguous (%d matches)", prefix, len(matches))
+	}
+}
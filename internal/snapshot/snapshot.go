@@ -0,0 +1,151 @@
+package snapshot
+
+import (
+	"agent/gorani/internal/config"
+	"agent/gorani/internal/journal"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxAge is how long a snapshot is used as the base of a model answer. Older ones are ignored,
+// so an answer pasted much later is applied to the file as it is.
+const MaxAge = 24 * time.Hour
+
+// Entry is the latest snapshot of a file.
+type Entry struct {
+	Hash string    `json:"hash"`
+	Time time.Time `json:"time"`
+}
+
+// Dir returns the snapshot directory, .gorani/snapshots.
+func Dir() string {
+	return config.StatePath("snapshots")
+}
+
+func indexPath() string {
+	return filepath.Join(Dir(), "index.json")
+}
+
+func objectPath(hash string) string {
+	return filepath.Join(Dir(), "objects", hash)
+}
+
+var mu sync.Mutex
+
+// Key normalizes a path the way snapshots are indexed.
+func Key(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// Record saves the content of a file as it is sent to the model, so that an answer can later
+// be merged with changes made to the file in the meantime. It returns the content hash.
+func Record(path string, content []byte) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	hash := journal.Hash(content)
+	object := objectPath(hash)
+	if _, err := os.Stat(object); err != nil {
+		if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %v", filepath.Dir(object), err)
+		}
+		if err := os.WriteFile(object, content, 0644); err != nil {
+			return "", fmt.Errorf("failed to save snapshot of %s: %v", path, err)
+		}
+	}
+
+	index := loadIndex()
+	index[Key(path)] = Entry{Hash: hash, Time: time.Now()}
+	prune(index)
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(indexPath(), append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to save snapshot index: %v", err)
+	}
+	return hash, nil
+}
+
+// Lookup returns the hash of the latest snapshot of a file younger than MaxAge.
+func Lookup(path string) (string, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	entry, ok := loadIndex()[Key(path)]
+	if !ok || time.Since(entry.Time) > MaxAge {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+// ShortLen is the length of the hash prefix grab puts in ">>> path (base <hash>)" headers.
+const ShortLen = 12
+
+// Short returns the prefix of a hash shown to the model.
+func Short(hash string) string {
+	return hash[:min(ShortLen, len(hash))]
+}
+
+// Expand returns the full hash of the snapshot whose hash starts with prefix.
+func Expand(prefix string) (string, bool) {
+	if prefix == "" {
+		return "", false
+	}
+	objects, err := os.ReadDir(filepath.Join(Dir(), "objects"))
+	if err != nil {
+		return "", false
+	}
+	found := ""
+	for _, object := range objects {
+		if strings.HasPrefix(object.Name(), prefix) {
+			if found != "" {
+				return "", false
+			}
+			found = object.Name()
+		}
+	}
+	return found, found != ""
+}
+
+// Content returns the content saved under a hash.
+func Content(hash string) ([]byte, error) {
+	data, err := os.ReadFile(objectPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s is missing: %v", hash, err)
+	}
+	return data, nil
+}
+
+func loadIndex() map[string]Entry {
+	index := map[string]Entry{}
+	if data, err := os.ReadFile(indexPath()); err == nil {
+		json.Unmarshal(data, &index)
+	}
+	return index
+}
+
+// prune drops expired entries and the contents no entry refers to.
+func prune(index map[string]Entry) {
+	used := map[string]bool{}
+	for path, entry := range index {
+		if time.Since(entry.Time) > MaxAge {
+			delete(index, path)
+			continue
+		}
+		used[entry.Hash] = true
+	}
+	objects, err := os.ReadDir(filepath.Join(Dir(), "objects"))
+	if err != nil {
+		return
+	}
+	for _, object := range objects {
+		if !used[object.Name()] {
+			os.Remove(objectPath(object.Name()))
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package templates
+
+import (
+	"agent/gorani/internal/config"
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Built-in templates, overridable by files of the same name in .gorani/prompts.
+//
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// Ext is the file extension of prompt templates.
+const Ext = ".tmpl"
+
+// Template sources reported by List.
+const (
+	SourceDefault  = "default"
+	SourceOverride = "override"
+	SourceCustom   = "custom"
+)
+
+// Data holds the variables available to prompt templates.
+type Data struct {
+	// Branch is the current git branch.
+	Branch string
+	// Feature is the feature name, usually the branch.
+	Feature     string
+	Description string
+	// Summary is the summary of Go symbols of the project.
+	Summary string
+	// Tree is the directory tree with functions.
+	Tree string
+	// Files are the selected files and their contents.
+	Files []File
+	// Diff is the uncommitted change against HEAD.
+	Diff string
+	// Errors reports what went wrong in a previous attempt, e.g. edits that did not apply.
+	Errors string
+}
+
+// File is a selected file passed to a template.
+type File struct {
+	Path    string
+	Content string
+}
+
+// Info describes an available template.
+type Info struct {
+	Name   string
+	Source string
+	// Path is the override file, empty for built-in templates.
+	Path string
+}
+
+// Dir returns the directory of template overrides, .gorani/prompts.
+func Dir() string {
+	return config.StatePath("prompts")
+}
+
+// Path returns the override file of the named template.
+func Path(name string) string {
+	return filepath.Join(Dir(), name+Ext)
+}
+
+// WithGit fills in the branch and diff of the working tree when they are empty.
+// Outside a git repository both stay empty.
+func (d Data) WithGit() Data {
+	if d.Branch == "" {
+		if output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+			d.Branch = strings.TrimSpace(string(output))
+		}
+	}
+	if d.Diff == "" {
+		if output, err := exec.Command("git", "diff", "HEAD").Output(); err == nil {
+			d.Diff = string(output)
+		}
+	}
+	return d
+}
+
+// Source returns the text of the named template and where it comes from,
+// preferring the override in .gorani/prompts.
+func Source(name string) (string, string, error) {
+	if data, err := os.ReadFile(Path(name)); err == nil {
+		source := SourceOverride
+		if _, err := defaults.ReadFile("defaults/" + name + Ext); err != nil {
+			source = SourceCustom
+		}
+		return string(data), source, nil
+	} else if !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read %s: %v", Path(name), err)
+	}
+
+	data, err := Default(name)
+	if err != nil {
+		return "", "", err
+	}
+	return data, SourceDefault, nil
+}
+
+// Default returns the built-in text of the named template.
+func Default(name string) (string, error) {
+	data, err := defaults.ReadFile("defaults/" + name + Ext)
+	if err != nil {
+		return "", fmt.Errorf("unknown prompt template %q: run 'gorani prompts list'", name)
+	}
+	return string(data), nil
+}
+
+// Parse checks that text is a valid template.
+func Parse(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// Render executes the named template with data.
+func Render(name string, data Data) (string, error) {
+	text, _, err := Source(name)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := Parse(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %v", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// List returns the built-in templates and any custom ones in .gorani/prompts, sorted by name.
+func List() ([]Info, error) {
+	infos := map[string]Info{}
+	entries, err := defaults.ReadDir("defaults")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), Ext)
+		infos[name] = Info{Name: name, Source: SourceDefault}
+	}
+
+	overrides, err := os.ReadDir(Dir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %v", Dir(), err)
+	}
+	for _, e := range overrides {
+		if e.IsDir() || filepath.Ext(e.Name()) != Ext {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), Ext)
+		source := SourceCustom
+		if _, ok := infos[name]; ok {
+			source = SourceOverride
+		}
+		infos[name] = Info{Name: name, Source: source, Path: Path(name)}
+	}
+
+	list := make([]Info, 0, len(infos))
+	for _, info := range infos {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Customize copies the named template to .gorani/prompts unless an override exists,
+// and returns the path to edit.
+func Customize(name string) (string, error) {
+	path := Path(name)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	text, err := Default(name)
+	if err != nil {
+		// New names start an empty custom template.
+		text = ""
+	}
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", Dir(), err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return path, nil
+}
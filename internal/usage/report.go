@@ -0,0 +1,167 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Totals sums a group of ledger records.
+type Totals struct {
+	Requests         int
+	CachedRequests   int
+	Errors           int
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	Cost             float64
+	Latency          time.Duration
+}
+
+func (t *Totals) add(r Record) {
+	t.Requests++
+	if r.Error != "" {
+		t.Errors++
+	}
+	t.Latency += time.Duration(r.LatencyMS) * time.Millisecond
+	// Cache hits sent no tokens; older ledgers still list those of the original call.
+	if r.Cached {
+		t.CachedRequests++
+		return
+	}
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.TotalTokens += r.TotalTokens
+	t.Cost += r.Cost
+}
+
+// String formats the totals on a single line.
+func (t Totals) String() string {
+	s := fmt.Sprintf("%d requests, %d prompt + %d completion tokens, $%.4f",
+		t.Requests, t.PromptTokens, t.CompletionTokens, t.Cost)
+	if t.CachedRequests > 0 {
+		s += fmt.Sprintf(", %d cached", t.CachedRequests)
+	}
+	if t.Errors > 0 {
+		s += fmt.Sprintf(", %d failed", t.Errors)
+	}
+	return s
+}
+
+// Groupings accepted by Summarize.
+const (
+	ByCommand = "command"
+	ByModel   = "model"
+	ByBranch  = "branch"
+	ByDay     = "day"
+)
+
+// Group is the totals of the records sharing a key.
+type Group struct {
+	Key string
+	Totals
+}
+
+// Summarize groups records by command, model, branch or day, most expensive first.
+func Summarize(records []Record, by string) ([]Group, error) {
+	keyOf := map[string]func(Record) string{
+		ByCommand: func(r Record) string { return r.Command },
+		ByModel:   func(r Record) string { return r.Model },
+		ByBranch:  func(r Record) string { return r.Branch },
+		ByDay:     func(r Record) string { return r.Time.Local().Format("2006-01-02") },
+	}[by]
+	if keyOf == nil {
+		return nil, fmt.Errorf("unknown grouping %q: use command, model, branch or day", by)
+	}
+
+	index := map[string]*Group{}
+	var groups []*Group
+	for _, r := range records {
+		key := keyOf(r)
+		if key == "" {
+			key = "(none)"
+		}
+		g, ok := index[key]
+		if !ok {
+			g = &Group{Key: key}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.add(r)
+	}
+
+	result := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if by == ByDay {
+			return result[i].Key < result[j].Key
+		}
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].TotalTokens > result[j].TotalTokens
+	})
+	return result, nil
+}
+
+// WriteTable prints groups and their grand total as an aligned table.
+func WriteTable(w io.Writer, by string, groups []Group) {
+	width := len(by)
+	var total Totals
+	for _, g := range groups {
+		if len(g.Key) > width {
+			width = len(g.Key)
+		}
+		total.Requests += g.Requests
+		total.CachedRequests += g.CachedRequests
+		total.Errors += g.Errors
+		total.PromptTokens += g.PromptTokens
+		total.CompletionTokens += g.CompletionTokens
+		total.TotalTokens += g.TotalTokens
+		total.Cost += g.Cost
+		total.Latency += g.Latency
+	}
+
+	row := func(key string, t Totals) {
+		avg := time.Duration(0)
+		if t.Requests > 0 {
+			avg = t.Latency / time.Duration(t.Requests)
+		}
+		fmt.Fprintf(w, "%-*s %8d %12d %12d %10s %9s\n",
+			width, key, t.Requests, t.PromptTokens, t.CompletionTokens,
+			"$"+strconv.FormatFloat(t.Cost, 'f', 4, 64), avg.Round(10*time.Millisecond))
+	}
+	fmt.Fprintf(w, "%-*s %8s %12s %12s %10s %9s\n", width, strings.ToUpper(by), "REQUESTS", "PROMPT", "COMPLETION", "COST", "AVG TIME")
+	for _, g := range groups {
+		row(g.Key, g.Totals)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", width+56))
+	row("total", total)
+}
+
+// ParseSince parses a duration such as "7d", "12h" or "30m", or a date such as "2024-10-01",
+// and returns the earliest time to include.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid --since %q", value)
+		}
+		return now.AddDate(0, 0, -n), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration like 7d or 12h, or a date like 2024-10-01", value)
+}
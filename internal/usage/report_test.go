@@ -0,0 +1,24 @@
+package usage
+
+import "testing"
+
+func TestTotalsLeaveOutCachedTokens(t *testing.T) {
+	records := []Record{
+		{Command: "gorani prompt", PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, Cost: 0.5},
+		// Older ledgers kept the token counts of the call a cached answer came from.
+		{Command: "gorani prompt", PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, Cached: true},
+		{Command: "gorani prompt", Cached: true},
+	}
+	groups, err := Summarize(records, ByCommand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(groups))
+	}
+	got := groups[0].Totals
+	want := Totals{Requests: 3, CachedRequests: 2, PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, Cost: 0.5}
+	if got != want {
+		t.Errorf("totals = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package usage
+
+import (
+	"agent/gorani/internal/config"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one LLM call in the usage ledger.
+type Record struct {
+	Time             time.Time `json:"time"`
+	Command          string    `json:"command"`
+	Branch           string    `json:"branch,omitempty"`
+	Model            string    `json:"model"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	// LatencyMS is the wall time of the call in milliseconds, including retries.
+	LatencyMS int64 `json:"latency_ms"`
+	// Cached is set when the answer was served without calling the model.
+	Cached bool    `json:"cached,omitempty"`
+	Cost   float64 `json:"cost"`
+	// Error is set when the call failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Path returns the ledger file, .gorani/usage.jsonl.
+func Path() string {
+	return config.StatePath("usage.jsonl")
+}
+
+var (
+	mu      sync.Mutex
+	command string
+	totals  Totals
+
+	branchOnce sync.Once
+	branch     string
+)
+
+// SetCommand names the command recorded with every following call, e.g. "gorani smartgrab".
+func SetCommand(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	command = name
+}
+
+// Add prices a call, appends it to the ledger and adds it to the totals of this run.
+// Failing to write the ledger only prints a warning.
+func Add(r Record) Record {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.Branch == "" {
+		r.Branch = currentBranch()
+	}
+	if settings, err := config.Load(); err == nil && !r.Cached {
+		r.Cost = Cost(settings, r.Model, r.PromptTokens, r.CompletionTokens)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if r.Command == "" {
+		r.Command = command
+	}
+	totals.add(r)
+	if err := appendRecord(r); err != nil {
+		fmt.Println("Warning: usage not recorded:", err)
+	}
+	return r
+}
+
+// Cost returns the price in dollars of the given token counts, or 0 when the model has no price.
+func Cost(settings *config.Settings, model string, promptTokens, completionTokens int64) float64 {
+	price, ok := settings.PriceFor(model)
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6
+}
+
+// Session returns the totals of the calls made by this process.
+func Session() Totals {
+	mu.Lock()
+	defer mu.Unlock()
+	return totals
+}
+
+// appendRecord writes a record to the ledger.
+func appendRecord(r Record) error {
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads the ledger records made at or after since. A missing ledger has no records.
+func Load(since time.Time) ([]Record, error) {
+	file, err := os.Open(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", Path(), line, err)
+		}
+		if !r.Time.Before(since) {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// currentBranch returns the checked out git branch, looked up once per process.
+func currentBranch() string {
+	branchOnce.Do(func() {
+		output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+		if err == nil {
+			branch = strings.TrimSpace(string(output))
+		}
+	})
+	return branch
+}
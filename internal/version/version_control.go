@@ -1,8 +1,8 @@
 package version
 
 import (
+	"agent/gorani/internal/journal"
 	"fmt"
-	"os"
 )
 
 // WriteReadme generates a README.md file with basic information.
@@ -21,14 +21,7 @@ How to use this project.
 ## License
 Specify the license details here.
 `
-	file, err := os.Create("README.md")
-	if err != nil {
-		return fmt.Errorf("failed to create README.md: %v", err)
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(readmeContent)
-	if err != nil {
+	if err := journal.WriteFile("README.md", []byte(readmeContent), 0644); err != nil {
 		return fmt.Errorf("failed to write to README.md: %v", err)
 	}
 